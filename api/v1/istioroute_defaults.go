@@ -0,0 +1,247 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ServiceType 별 기본값
+const (
+	// DefaultCanaryRatio Canary/StickyCanary 에서 ratio 미지정 시 신규 버전 비율
+	DefaultCanaryRatio = 0
+	// DefaultMirrorPercentage MirrorType 에서 mirror 미지정 시 섀도 커밋으로 복사할 비율
+	DefaultMirrorPercentage = 100
+	// DefaultPreviewHeader BlueGreenType preview 커밋 선택 헤더
+	DefaultPreviewHeader = "x-preview"
+	// DefaultOutlierDetectionInterval Istio 기본값과 동일한 이상 감지 주기
+	DefaultOutlierDetectionInterval = "10s"
+	// DefaultOutlierBaseEjectionTime 최소 제외 시간
+	DefaultOutlierBaseEjectionTime = "30s"
+	// DefaultOutlierMaxEjectionPercent 제외 가능한 최대 호스트 비율
+	DefaultOutlierMaxEjectionPercent = 100
+	// DefaultOutlierMinHealthPercent 이상 감지를 유지할 최소 정상 호스트 비율
+	DefaultOutlierMinHealthPercent = 50
+	// DefaultStickyHeader 기존 동작과 동일하게 jwt 헤더 값으로 고정
+	DefaultStickyHeader = "jwt"
+	// DefaultStickyTokenHeader JWTClaim 소스의 토큰 헤더
+	DefaultStickyTokenHeader = "authorization"
+	// DefaultDarknessUserClaim 다크니스 릴리즈 UserIDs 와 비교할 JWT claim
+	DefaultDarknessUserClaim = "sub"
+	// DefaultDarknessTokenHeader 다크니스 릴리즈 UserIDs 용 토큰 헤더
+	DefaultDarknessTokenHeader = "authorization"
+	// DefaultGatewayName 기존에 고정으로 생성하던 게이트웨이 이름
+	DefaultGatewayName = "istio-gateway"
+	// DefaultGatewayNamespace 기존에 고정으로 생성하던 게이트웨이 namespace
+	DefaultGatewayNamespace = "default"
+	// DefaultIngressRewrite 게이트웨이에서 매칭된 경로를 바꿀 값
+	DefaultIngressRewrite = "/api"
+	// DefaultAnalysisInterval 메트릭 분석 주기
+	DefaultAnalysisInterval = "1m"
+	// DefaultAnalysisSuccessThreshold 신규 커밋 전환에 필요한 연속 성공 횟수
+	DefaultAnalysisSuccessThreshold = 5
+)
+
+// SetDefaults 비어 있는 필드를 ServiceType 에 맞는 기본값으로 채움.
+// 웹훅과 컨트롤러가 같은 규칙을 사용하도록 여기서만 정의
+func (in *IstioRoute) SetDefaults() {
+	for i := range in.Spec.Services {
+		in.Spec.Services[i].SetDefaults(in.Namespace)
+	}
+
+	if in.Spec.Gateway == nil {
+		in.Spec.Gateway = &GatewayConfig{}
+	}
+	in.Spec.Gateway.SetDefaults()
+}
+
+// SetDefaults 미지정 항목은 기존에 고정으로 생성하던 게이트웨이(default/istio-gateway, 80 HTTP)와 동일하게
+func (in *GatewayConfig) SetDefaults() {
+	if in.Name == "" {
+		in.Name = DefaultGatewayName
+	}
+	if in.Namespace == "" {
+		in.Namespace = DefaultGatewayNamespace
+	}
+	if len(in.Selector) == 0 {
+		in.Selector = map[string]string{"istio": "ingressgateway"}
+	}
+	if len(in.Servers) == 0 {
+		in.Servers = []GatewayServer{{Port: 80}}
+	}
+	for i := range in.Servers {
+		server := &in.Servers[i]
+		if server.Protocol == "" {
+			server.Protocol = GatewayHTTP
+		}
+		if server.Name == "" {
+			server.Name = fmt.Sprintf("%s-%d", strings.ToLower(string(server.Protocol)), server.Port)
+		}
+		if len(server.Hosts) == 0 {
+			server.Hosts = []string{"*"}
+		}
+	}
+}
+
+// SetDefaults namespace 가 비어 있으면 IstioRoute 의 namespace 를 상속
+func (in *ServiceConfig) SetDefaults(namespace string) {
+	if in.Namespace == "" {
+		in.Namespace = namespace
+	}
+
+	switch in.Type {
+	case CanaryType, StickyCanaryType:
+		// Weights 를 사용하면 Ratio 는 비워 둠
+		if in.Ratio == nil && len(in.Weights) == 0 {
+			ratio := DefaultCanaryRatio
+			in.Ratio = &ratio
+		}
+	}
+
+	if in.Type == StickyCanaryType {
+		if in.StickySession == nil {
+			in.StickySession = &StickySession{Source: StickyHeaderSource, Name: DefaultStickyHeader}
+		}
+		in.StickySession.SetDefaults()
+	}
+
+	if in.Type == BlueGreenType {
+		if in.BlueGreen == nil {
+			in.BlueGreen = &BlueGreen{}
+		}
+		if in.BlueGreen.ActiveCommitHash == "" && len(in.CommitHashes) > 0 {
+			in.BlueGreen.ActiveCommitHash = in.CommitHashes[0]
+		}
+		if in.BlueGreen.PreviewHeader == "" {
+			in.BlueGreen.PreviewHeader = DefaultPreviewHeader
+		}
+	}
+
+	if in.Type == ABTestType && in.ABTest != nil && in.ABTest.DefaultCommitHash == "" && len(in.CommitHashes) > 0 {
+		in.ABTest.DefaultCommitHash = in.CommitHashes[0]
+	}
+
+	if in.Type == MirrorType {
+		if in.Mirror == nil {
+			in.Mirror = &Mirror{}
+		}
+		if in.Mirror.Percentage == nil {
+			percentage := DefaultMirrorPercentage
+			in.Mirror.Percentage = &percentage
+		}
+	}
+
+	if in.OutlierDetection != nil {
+		in.OutlierDetection.SetDefaults()
+	}
+
+	if in.Ingress != nil {
+		in.Ingress.SetDefaults(in.Name)
+	}
+
+	if in.Analysis != nil {
+		if in.Analysis.Interval == "" {
+			in.Analysis.Interval = DefaultAnalysisInterval
+		}
+		if in.Analysis.SuccessThreshold == 0 {
+			in.Analysis.SuccessThreshold = DefaultAnalysisSuccessThreshold
+		}
+	}
+
+	for i := range in.DarknessReleases {
+		in.DarknessReleases[i].SetDefaults()
+	}
+
+	for i := range in.Dependencies {
+		if in.Dependencies[i].Namespace == "" {
+			in.Dependencies[i].Namespace = namespace
+		}
+	}
+}
+
+// SetDefaults 키가 없는 요청은 기존 커밋으로 보내고 JWT 는 authorization 헤더에서 읽음
+func (in *StickySession) SetDefaults() {
+	if in.Fallback == "" {
+		in.Fallback = StickyFallbackStable
+	}
+	if in.Source == StickyJWTClaimSource && in.TokenHeader == "" {
+		in.TokenHeader = DefaultStickyTokenHeader
+	}
+}
+
+// SetDefaults UserIDs 가 있으면 authorization 헤더 JWT 의 sub claim 과 비교
+func (in *DarknessRelease) SetDefaults() {
+	if len(in.UserIDs) == 0 {
+		return
+	}
+	if in.UserClaim == "" {
+		in.UserClaim = DefaultDarknessUserClaim
+	}
+	if in.TokenHeader == "" {
+		in.TokenHeader = DefaultDarknessTokenHeader
+	}
+}
+
+// SetDefaults 기존에 고정값으로 쓰던 /<서비스명> prefix 와 /api rewrite 를 기본값으로 사용
+func (in *IngressConfig) SetDefaults(serviceName string) {
+	if in.PathType == "" {
+		in.PathType = IngressPathPrefix
+	}
+	if in.Path == "" {
+		in.Path = "/" + serviceName
+	}
+	if in.Rewrite == nil {
+		rewrite := DefaultIngressRewrite
+		in.Rewrite = &rewrite
+	}
+}
+
+// SetDefaults 기존에 고정값으로 쓰던 제외 시간/비율을 기본값으로 사용
+func (in *OutlierDetection) SetDefaults() {
+	if in.Interval == "" {
+		in.Interval = DefaultOutlierDetectionInterval
+	}
+	if in.BaseEjectionTime == "" {
+		in.BaseEjectionTime = DefaultOutlierBaseEjectionTime
+	}
+	if in.MaxEjectionPercent == nil {
+		percent := int32(DefaultOutlierMaxEjectionPercent)
+		in.MaxEjectionPercent = &percent
+	}
+	if in.MinHealthPercent == nil {
+		percent := int32(DefaultOutlierMinHealthPercent)
+		in.MinHealthPercent = &percent
+	}
+}
+
+// CommitWeights 커밋별 비율. Weights 가 없으면 기존 두 커밋 형식(CommitHashes + Ratio)을 변환
+func (in *ServiceConfig) CommitWeights() []CommitWeight {
+	if len(in.Weights) > 0 {
+		return in.Weights
+	}
+	if len(in.CommitHashes) == 0 {
+		return nil
+	}
+	if len(in.CommitHashes) < 2 || in.Ratio == nil {
+		return []CommitWeight{{CommitHash: in.CommitHashes[0], Weight: 100}}
+	}
+	return []CommitWeight{
+		{CommitHash: in.CommitHashes[0], Weight: 100 - *in.Ratio},
+		{CommitHash: in.CommitHashes[1], Weight: *in.Ratio},
+	}
+}
@@ -26,15 +26,72 @@ import (
 // IstioRouteSpec defines the desired state of IstioRoute
 type IstioRouteSpec struct {
 	Services []ServiceConfig `json:"services"`
+
+	// ingress VirtualService 가 사용할 Istio Gateway. 미지정 시 default/istio-gateway (80 HTTP, 모든 호스트)
+	// +kubebuilder:validation:Optional
+	Gateway *GatewayConfig `json:"gateway,omitempty"`
+}
+
+type GatewayConfig struct {
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// 게이트웨이 워크로드 label. Lua EnvoyFilter 의 workloadSelector 에도 사용
+	// +kubebuilder:validation:Optional
+	Selector map[string]string `json:"selector,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Servers []GatewayServer `json:"servers,omitempty"`
+}
+
+// GatewayProtocol 게이트웨이 리스너 프로토콜
+type GatewayProtocol string
+
+const (
+	GatewayHTTP  GatewayProtocol = "HTTP"
+	GatewayHTTPS GatewayProtocol = "HTTPS"
+)
+
+// GatewayServer 게이트웨이 리스너 하나
+type GatewayServer struct {
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// 미지정 시 <protocol>-<port> (예: http-80)
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
+
+	// +kubebuilder:validation:Enum=HTTP;HTTPS
+	// +kubebuilder:validation:Optional
+	Protocol GatewayProtocol `json:"protocol,omitempty"`
+
+	// 미지정 시 모든 호스트("*")
+	// +kubebuilder:validation:Optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// HTTPS 인증서 secret 이름 (게이트웨이 워크로드 namespace 기준)
+	// +kubebuilder:validation:Optional
+	CredentialName string `json:"credentialName,omitempty"`
+
+	// HTTP 리스너로 들어온 요청을 HTTPS 로 리다이렉트
+	// +kubebuilder:validation:Optional
+	HTTPSRedirect bool `json:"httpsRedirect,omitempty"`
 }
 
 type ServiceConfig struct {
-	Name      string      `json:"name"`
+	Name string `json:"name"`
+
+	// 비어 있으면 IstioRoute 의 namespace 사용
+	// +optional
 	Namespace string      `json:"namespace"`
 	Type      ServiceType `json:"type"` // Canary, StickyCanary
 
 	// +kubebuilder:validation:MinItems=1
-	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:MaxItems=10
 	CommitHashes []string `json:"commitHashes,omitempty"`
 
 	// +kubebuilder:validation:Minimum=0
@@ -42,16 +99,88 @@ type ServiceConfig struct {
 	// +kubebuilder:validation:nullable
 	Ratio *int `json:"ratio,omitempty"`
 
+	// 버전 헤더가 없는 요청(메시 내부 호출 등)을 보낼 커밋. 미지정 시 다크니스 릴리즈가 아닌 첫 번째 커밋
+	// +kubebuilder:validation:Optional
+	FallbackCommitHash string `json:"fallbackCommitHash,omitempty"`
+
+	// CanaryType 의 버전 선택 방식. 미지정 시 Header
+	// +kubebuilder:validation:Enum=Header;Weighted
+	// +kubebuilder:validation:Optional
+	RoutingMode RoutingMode `json:"routingMode,omitempty"`
+
+	// Canary/StickyCanary 에서 커밋별 트래픽 비율 (합계 100). 세 개 이상의 버전을 동시에 운영할 때 Ratio 대신 사용
+	// +kubebuilder:validation:Optional
+	Weights []CommitWeight `json:"weights,omitempty"`
+
 	Dependencies []Dependency `json:"dependencies,omitempty"`
 
+	// StickyCanary 에서 처음 정해진 버전을 쿠키로 유지하는 시간(초). 미지정 시 쿠키를 발급하지 않고 stickySession 키로만 고정
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:validation:Maximum=3600
 	SessionDuration int `json:"sessionDuration,omitempty"`
 
+	// StickyCanary 에서 버전을 고정할 키. 미지정 시 jwt 헤더
+	// +kubebuilder:validation:Optional
+	StickySession *StickySession `json:"stickySession,omitempty"`
+
 	OutlierDetection *OutlierDetection `json:"outlierDetection,omitempty"`
 
+	// MirrorType 에서 섀도 커밋(CommitHashes[1])으로 복사할 트래픽 비율
+	// +kubebuilder:validation:Optional
+	Mirror *Mirror `json:"mirror,omitempty"`
+
+	// BlueGreenType 의 active/preview 설정. activeCommitHash 변경으로 즉시 전환
+	// +kubebuilder:validation:Optional
+	BlueGreen *BlueGreen `json:"blueGreen,omitempty"`
+
+	// ABTestType 의 커밋 선택 규칙
+	// +kubebuilder:validation:Optional
+	ABTest *ABTest `json:"abTest,omitempty"`
+
+	// 게이트웨이(-ingress VirtualService)와 Lua 필터가 사용하는 외부 호스트/경로
+	// +kubebuilder:validation:Optional
+	Ingress *IngressConfig `json:"ingress,omitempty"`
+
+	// 생성되는 모든 라우트의 요청 타임아웃 (예: 5s)
+	// +kubebuilder:validation:Pattern=`^([0-9]+(ms|s|m|h))+$`
+	// +kubebuilder:validation:Optional
+	Timeout string `json:"timeout,omitempty"`
+
+	// 생성되는 모든 라우트의 재시도 정책
+	// +kubebuilder:validation:Optional
+	Retries *RetryPolicy `json:"retries,omitempty"`
+
+	// 지연/오류 주입. CommitHash 를 지정하면 해당 커밋으로 가는 라우트에만 적용
+	// +kubebuilder:validation:Optional
+	Fault *FaultInjection `json:"fault,omitempty"`
+
+	// DestinationRule 연결 풀/로드밸런서 설정. 커밋별로 덮어쓸 수 있음
+	// +kubebuilder:validation:Optional
+	TrafficPolicy *TrafficPolicy `json:"trafficPolicy,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	DarknessReleases []DarknessRelease `json:"darknessReleases,omitempty"`
+
+	// Canary/StickyCanary 자동 롤아웃 단계. 지정 시 Ratio 대신 컨트롤러가 단계별 비율을 적용하고
+	// 마지막 단계가 끝나면 신규 커밋으로 100% 전환
+	// +kubebuilder:validation:Optional
+	Steps []CanaryStep `json:"steps,omitempty"`
+
+	// 신규 커밋(CommitHashes[1]) 메트릭 분석 규칙. 임계값을 넘으면 기존 커밋으로 롤백,
+	// Steps 가 없을 때는 연속 성공 횟수를 채우면 신규 커밋으로 전환
+	// +kubebuilder:validation:Optional
+	Analysis *Analysis `json:"analysis,omitempty"`
+}
+
+type CanaryStep struct {
+	// 신규 커밋(CommitHashes[1])으로 보낼 비율
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int `json:"weight"`
+
+	// 해당 단계 유지 시간
+	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	Duration string `json:"duration"`
 }
 
 type ServiceType string
@@ -60,21 +189,355 @@ const (
 	StandardType     ServiceType = "StandardType"
 	CanaryType       ServiceType = "CanaryType"
 	StickyCanaryType ServiceType = "StickyCanaryType"
+	// MirrorType CommitHashes[0] 이 응답하고 CommitHashes[1] 에는 트래픽 복사본만 전송
+	MirrorType ServiceType = "MirrorType"
+	// BlueGreenType 운영 트래픽은 active 커밋으로만 보내고 다른 커밋은 preview 헤더/경로로 접근
+	BlueGreenType ServiceType = "BlueGreenType"
+	// ABTestType 헤더/쿼리 파라미터/User-Agent 규칙으로 커밋을 선택
+	ABTestType ServiceType = "ABTestType"
 )
 
+type Analysis struct {
+	// Prometheus 호환 쿼리 API 주소. 비어 있으면 PROMETHEUS_URL 환경변수 사용
+	// +kubebuilder:validation:Optional
+	Address string `json:"address,omitempty"`
+
+	// 평가 주기이자 rate() 구간
+	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	// +kubebuilder:default="1m"
+	Interval string `json:"interval,omitempty"`
+
+	// 롤백 전까지 허용하는 연속 실패 횟수
+	// +kubebuilder:validation:Minimum=0
+	FailureLimit int `json:"failureLimit,omitempty"`
+
+	// Steps 가 없을 때 신규 커밋으로 전환하기 위한 연속 성공 횟수
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=5
+	SuccessThreshold int `json:"successThreshold,omitempty"`
+
+	// +kubebuilder:validation:MinItems=1
+	Rules []AnalysisRule `json:"rules"`
+}
+
+type AnalysisMetric string
+
+const (
+	// ErrorRateMetric 5xx 응답 비율 (0~1)
+	ErrorRateMetric AnalysisMetric = "ErrorRate"
+	// LatencyP99Metric p99 응답 시간 (ms)
+	LatencyP99Metric AnalysisMetric = "LatencyP99"
+	// CustomMetric Query 필드의 PromQL 사용
+	CustomMetric AnalysisMetric = "Custom"
+)
+
+type AnalysisRule struct {
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Enum=ErrorRate;LatencyP99;Custom
+	Metric AnalysisMetric `json:"metric"`
+
+	// Custom 전용 PromQL. {{service}}, {{namespace}}, {{version}}, {{interval}} 치환
+	// +kubebuilder:validation:Optional
+	Query string `json:"query,omitempty"`
+
+	// 이 값을 넘으면 실패
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	Max string `json:"max"`
+}
+
+type StickySessionSource string
+
+const (
+	StickyHeaderSource   StickySessionSource = "Header"
+	StickyCookieSource   StickySessionSource = "Cookie"
+	StickyJWTClaimSource StickySessionSource = "JWTClaim"
+	StickyClientIPSource StickySessionSource = "ClientIP"
+)
+
+type StickyFallback string
+
+const (
+	// StickyFallbackStable 키가 없는 요청은 기존 커밋(CommitHashes[0])으로
+	StickyFallbackStable StickyFallback = "Stable"
+	// StickyFallbackRandom 키가 없는 요청은 Ratio 에 따라 무작위로
+	StickyFallbackRandom StickyFallback = "Random"
+)
+
+type StickySession struct {
+	// +kubebuilder:validation:Enum=Header;Cookie;JWTClaim;ClientIP
+	Source StickySessionSource `json:"source"`
+
+	// Header/Cookie 이름 또는 JWT claim 이름 (예: sub). ClientIP 에서는 사용하지 않음
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.-]+$`
+	Name string `json:"name,omitempty"`
+
+	// JWTClaim 에서 토큰을 읽을 헤더. "Bearer " 접두어는 제거
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.-]+$`
+	TokenHeader string `json:"tokenHeader,omitempty"`
+
+	// 키가 없는 요청 처리 방식
+	// +kubebuilder:validation:Enum=Stable;Random
+	Fallback StickyFallback `json:"fallback,omitempty"`
+}
+
 type OutlierDetection struct {
+	// 연속 5xx 응답 횟수. 0 이면 사용 안 함, 미지정 시 Istio 기본값(5)
 	// +kubebuilder:validation:Minimum=0
-	Consecutive5xxErrors int `json:"consecutive5xxErrors,omitempty"`
+	// +kubebuilder:validation:Optional
+	Consecutive5xxErrors *int `json:"consecutive5xxErrors,omitempty"`
 
+	// 연속 502/503/504 응답 횟수. 0 이면 사용 안 함
 	// +kubebuilder:validation:Minimum=0
 	ConsecutiveGatewayErrors int `json:"consecutiveGatewayErrors,omitempty"`
 
-	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	// 연결 실패/타임아웃 등 로컬 오류 연속 횟수. SplitExternalLocalOriginErrors 가 true 일 때만 사용
+	// +kubebuilder:validation:Minimum=0
+	ConsecutiveLocalOriginFailures int `json:"consecutiveLocalOriginFailures,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	SplitExternalLocalOriginErrors bool `json:"splitExternalLocalOriginErrors,omitempty"`
+
+	// +kubebuilder:validation:Pattern=`^([0-9]+(ms|s|m|h))+$`
 	Interval string `json:"interval,omitempty"`
+
+	// 최소 제외 시간. 제외될 때마다 횟수만큼 늘어남. 기본값 30s
+	// +kubebuilder:validation:Pattern=`^([0-9]+(ms|s|m|h))+$`
+	BaseEjectionTime string `json:"baseEjectionTime,omitempty"`
+
+	// 제외 가능한 최대 호스트 비율. 기본값 100
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxEjectionPercent *int32 `json:"maxEjectionPercent,omitempty"`
+
+	// 정상 호스트 비율이 이 값 미만이면 이상 감지 중단. 기본값 50
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MinHealthPercent *int32 `json:"minHealthPercent,omitempty"`
+}
+
+type Mirror struct {
+	// 미지정 시 100
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:validation:Optional
+	Percentage *int `json:"percentage,omitempty"`
+}
+
+type BlueGreen struct {
+	// 운영 트래픽을 받는 커밋. CommitHashes 중 하나이며 미지정 시 CommitHashes[0]
+	// +kubebuilder:validation:Optional
+	ActiveCommitHash string `json:"activeCommitHash,omitempty"`
+
+	// 값이 "true" 인 요청을 preview 커밋으로 보내는 헤더. 기본값 x-preview
+	// +kubebuilder:validation:Pattern=`^[a-z0-9-]+$`
+	// +kubebuilder:validation:Optional
+	PreviewHeader string `json:"previewHeader,omitempty"`
+
+	// 게이트웨이에서 preview 커밋으로 보내는 경로 prefix (예: /preview/user)
+	// +kubebuilder:validation:Pattern=`^/.*`
+	// +kubebuilder:validation:Optional
+	PreviewPathPrefix string `json:"previewPathPrefix,omitempty"`
+}
+
+type ABTest struct {
+	// 위에서부터 순서대로 평가하며 처음 일치한 규칙의 커밋으로 라우팅
+	// +kubebuilder:validation:MinItems=1
+	Rules []ABTestRule `json:"rules"`
+
+	// 어떤 규칙에도 맞지 않는 요청의 커밋. 미지정 시 CommitHashes[0]
+	// +kubebuilder:validation:Optional
+	DefaultCommitHash string `json:"defaultCommitHash,omitempty"`
+}
+
+// ABTestRule 지정한 조건을 모두 만족하면 CommitHash 로 라우팅
+type ABTestRule struct {
+	// +kubebuilder:validation:Required
+	CommitHash string `json:"commitHash"`
+
+	// +kubebuilder:validation:Optional
+	Headers []ValueMatch `json:"headers,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	QueryParams []ValueMatch `json:"queryParams,omitempty"`
+
+	// User-Agent 정규식 (RE2)
+	// +kubebuilder:validation:Optional
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// IngressPathType 외부 경로 매칭 방식
+type IngressPathType string
+
+const (
+	// IngressPathPrefix 세그먼트 단위 prefix. /user 는 /user, /user/... 에만 매칭되고 /users 에는 매칭되지 않음
+	IngressPathPrefix IngressPathType = "Prefix"
+	IngressPathExact  IngressPathType = "Exact"
+	// IngressPathRegex RE2 정규식. Lua 필터는 정규식의 리터럴 prefix 로만 판단
+	IngressPathRegex IngressPathType = "Regex"
+)
+
+type IngressConfig struct {
+	// 외부 호스트. 미지정 시 모든 호스트("*")
+	// +kubebuilder:validation:Optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// +kubebuilder:validation:Enum=Prefix;Exact;Regex
+	// +kubebuilder:validation:Optional
+	PathType IngressPathType `json:"pathType,omitempty"`
+
+	// 미지정 시 /<서비스명>
+	// +kubebuilder:validation:Optional
+	Path string `json:"path,omitempty"`
+
+	// 매칭된 경로를 바꿀 값. 미지정 시 /api, 빈 문자열이면 경로를 그대로 전달
+	// +kubebuilder:validation:Optional
+	Rewrite *string `json:"rewrite,omitempty"`
+}
+
+// RoutingMode 커밋 간 트래픽 분배 방식
+type RoutingMode string
+
+const (
+	// HeaderRoutingMode 게이트웨이 Lua 가 x-canary-version 헤더를 붙이고 VirtualService 는 헤더로 라우팅
+	HeaderRoutingMode RoutingMode = "Header"
+	// WeightedRoutingMode VirtualService 가중치로 분배. EnvoyFilter 없이 메시 내부 트래픽에도 적용
+	WeightedRoutingMode RoutingMode = "Weighted"
+)
+
+// CommitWeight 커밋 하나가 받는 트래픽 비율
+type CommitWeight struct {
+	// +kubebuilder:validation:Required
+	CommitHash string `json:"commitHash"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int `json:"weight"`
+}
+
+// ValueMatch Exact 와 Regex 중 하나만 지정
+type ValueMatch struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Optional
+	Exact string `json:"exact,omitempty"`
+
+	// RE2 정규식
+	// +kubebuilder:validation:Optional
+	Regex string `json:"regex,omitempty"`
+}
+
+type RetryPolicy struct {
+	// +kubebuilder:validation:Minimum=0
+	Attempts int `json:"attempts"`
+
+	// +kubebuilder:validation:Pattern=`^([0-9]+(ms|s|m|h))+$`
+	// +kubebuilder:validation:Optional
+	PerTryTimeout string `json:"perTryTimeout,omitempty"`
+
+	// Envoy retry 조건 (예: 5xx,gateway-error,connect-failure)
+	// +kubebuilder:validation:Optional
+	RetryOn string `json:"retryOn,omitempty"`
+}
+
+type FaultInjection struct {
+	// 비어 있으면 서비스의 모든 라우트에 적용. Weighted 라우팅에서는 지정할 수 없음
+	// +kubebuilder:validation:Optional
+	CommitHash string `json:"commitHash,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Delay *FaultDelay `json:"delay,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Abort *FaultAbort `json:"abort,omitempty"`
+}
+
+type FaultDelay struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^([0-9]+(ms|s|m|h))+$`
+	FixedDelay string `json:"fixedDelay"`
+
+	// 지연을 적용할 요청 비율
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percentage int `json:"percentage"`
+}
+
+type FaultAbort struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=200
+	// +kubebuilder:validation:Maximum=599
+	HTTPStatus int `json:"httpStatus"`
+
+	// 오류를 반환할 요청 비율
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percentage int `json:"percentage"`
+}
+
+type LoadBalancerType string
+
+const (
+	RoundRobinLoadBalancer   LoadBalancerType = "RoundRobin"
+	LeastRequestLoadBalancer LoadBalancerType = "LeastRequest"
+	RandomLoadBalancer       LoadBalancerType = "Random"
+)
+
+type TrafficPolicy struct {
+	// +kubebuilder:validation:Optional
+	ConnectionPool *ConnectionPool `json:"connectionPool,omitempty"`
+
+	// StickyCanary 는 ConsistentHash 를 사용하므로 지정 불가
+	// +kubebuilder:validation:Enum=RoundRobin;LeastRequest;Random
+	// +kubebuilder:validation:Optional
+	LoadBalancer LoadBalancerType `json:"loadBalancer,omitempty"`
+
+	// 커밋 해시(서브셋)별 설정. 지정한 항목만 서비스 설정을 덮어씀
+	// +kubebuilder:validation:Optional
+	Subsets []SubsetTrafficPolicy `json:"subsets,omitempty"`
+}
+
+type SubsetTrafficPolicy struct {
+	// +kubebuilder:validation:Required
+	CommitHash string `json:"commitHash"`
+
+	// +kubebuilder:validation:Optional
+	ConnectionPool *ConnectionPool `json:"connectionPool,omitempty"`
+
+	// +kubebuilder:validation:Enum=RoundRobin;LeastRequest;Random
+	// +kubebuilder:validation:Optional
+	LoadBalancer LoadBalancerType `json:"loadBalancer,omitempty"`
+}
+
+// ConnectionPool 0 인 항목은 Istio 기본값 사용
+type ConnectionPool struct {
+	// 최대 TCP 연결 수
+	// +kubebuilder:validation:Minimum=0
+	MaxConnections int `json:"maxConnections,omitempty"`
+
+	// 대기 중인 최대 요청 수
+	// +kubebuilder:validation:Minimum=0
+	MaxPendingRequests int `json:"maxPendingRequests,omitempty"`
+
+	// 동시에 처리할 최대 요청 수
+	// +kubebuilder:validation:Minimum=0
+	MaxRequests int `json:"maxRequests,omitempty"`
+
+	// 연결당 최대 요청 수. 1 이면 keep-alive 사용 안 함
+	// +kubebuilder:validation:Minimum=0
+	MaxRequestsPerConnection int `json:"maxRequestsPerConnection,omitempty"`
+
+	// 유휴 연결 종료 시간 (예: 30s)
+	// +kubebuilder:validation:Pattern=`^([0-9]+(ms|s|m|h))+$`
+	IdleTimeout string `json:"idleTimeout,omitempty"`
 }
 
 type Dependency struct {
-	Name      string `json:"name"`
+	Name string `json:"name"`
+
+	// 비어 있으면 IstioRoute 의 namespace 사용
+	// +optional
 	Namespace string `json:"namespace"`
 
 	// +kubebuilder:validation:Required
@@ -86,19 +549,201 @@ type DarknessRelease struct {
 	// +kubebuilder:validation:Required
 	CommitHash string `json:"commitHash"`
 
-	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:MaxItems=10
+	// 단일 IP(IPv4/IPv6) 또는 CIDR (예: 10.20.0.0/16, 2001:db8::/32)
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=256
 	IPs []string `json:"ips,omitempty"`
+
+	// 헤더 값이 일치하는 요청 (IPs 등 다른 조건과 OR)
+	// +kubebuilder:validation:Optional
+	Headers []DarknessHeaderMatch `json:"headers,omitempty"`
+
+	// 쿠키 값이 일치하는 요청
+	// +kubebuilder:validation:Optional
+	Cookies []DarknessCookieMatch `json:"cookies,omitempty"`
+
+	// JWT claim(UserClaim) 값이 목록에 포함된 사용자
+	// +kubebuilder:validation:Optional
+	UserIDs []string `json:"userIds,omitempty"`
+
+	// UserIDs 와 비교할 claim. 기본값 sub
+	// +kubebuilder:validation:Optional
+	UserClaim string `json:"userClaim,omitempty"`
+
+	// JWT 를 읽을 헤더. 기본값 authorization (Bearer 접두어 허용)
+	// +kubebuilder:validation:Optional
+	TokenHeader string `json:"tokenHeader,omitempty"`
+}
+
+type DarknessHeaderMatch struct {
+	// 소문자 헤더 이름
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9-]+$`
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
 }
 
+type DarknessCookieMatch struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.-]+$`
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
+}
+
+// IstioRoute 전체 상태를 나타내는 Condition 타입
+const (
+	ConditionReady       = "Ready"
+	ConditionDegraded    = "Degraded"
+	ConditionProgressing = "Progressing"
+	// ConditionFieldConflict 다른 field manager 가 소유한 필드와 충돌해 적용하지 못한 경우 True
+	ConditionFieldConflict = "FieldConflict"
+)
+
+// Condition Reason
+const (
+	ReasonApplied          = "Applied"
+	ReasonApplyFailed      = "ApplyFailed"
+	ReasonManifestsChanged = "ManifestsChanged"
+	ReasonUpToDate         = "UpToDate"
+
+	ReasonRolloutInProgress = "RolloutInProgress"
+	// ReasonInvalidSpec 웹훅을 거치지 않은 값 등으로 리소스를 생성하지 못한 경우
+	ReasonInvalidSpec = "InvalidSpec"
+	// ReasonFieldManagerConflict server-side apply 충돌. 덮어쓰지 않고 중단
+	ReasonFieldManagerConflict = "FieldManagerConflict"
+)
+
 // IstioRouteStatus defines the observed state of IstioRoute
 type IstioRouteStatus struct {
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	Conditions      []metav1.Condition `json:"conditions,omitempty"`
 	LastAppliedHash string             `json:"lastAppliedHash,omitempty"`
+
+	// +optional
+	Services []ServiceStatus `json:"services,omitempty"`
+
+	// spec 에서 빠져 삭제된 리소스 (최근 항목부터)
+	// +optional
+	PrunedResources []PrunedResource `json:"prunedResources,omitempty"`
+
+	// spec 에서 빠졌지만 hold 어노테이션으로 삭제를 보류한 리소스
+	// +optional
+	HeldResources []ResourceRef `json:"heldResources,omitempty"`
+}
+
+// ResourceRef 컨트롤러가 생성한 Istio 리소스
+type ResourceRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// PrunedResource 정리된 리소스와 삭제 시각
+type PrunedResource struct {
+	ResourceRef `json:",inline"`
+	PrunedAt    metav1.Time `json:"prunedAt"`
+}
+
+// ServiceStatus 는 Spec.Services 항목 하나에 대해 실제로 적용된 결과
+type ServiceStatus struct {
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace"`
+	Type      ServiceType `json:"type,omitempty"`
+
+	// 생성된 리소스 이름
+	VirtualService        string `json:"virtualService,omitempty"`
+	IngressVirtualService string `json:"ingressVirtualService,omitempty"`
+	DestinationRule       string `json:"destinationRule,omitempty"`
+	EnvoyFilter           string `json:"envoyFilter,omitempty"`
+
+	// 실제 적용된 커밋 해시와 canary 비율
+	AppliedCommitHashes []string `json:"appliedCommitHashes,omitempty"`
+	EffectiveRatio      *int     `json:"effectiveRatio,omitempty"`
+
+	// Weights 를 사용하는 경우 적용된 커밋별 비율
+	// +optional
+	EffectiveWeights []CommitWeight `json:"effectiveWeights,omitempty"`
+
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Steps 가 지정된 경우 롤아웃 진행 상태
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// Analysis 가 지정된 경우 분석 진행 상태
+	// +optional
+	Analysis *AnalysisStatus `json:"analysis,omitempty"`
+
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type RolloutPhase string
+
+const (
+	RolloutProgressing RolloutPhase = "Progressing"
+	RolloutPromoted    RolloutPhase = "Promoted"
+)
+
+type AnalysisPhase string
+
+const (
+	AnalysisRunning  AnalysisPhase = "Running"
+	AnalysisPromoted AnalysisPhase = "Promoted"
+	AnalysisAborted  AnalysisPhase = "Aborted"
+)
+
+type AnalysisStatus struct {
+	Phase AnalysisPhase `json:"phase"`
+
+	// 분석 대상 신규 커밋. 바뀌면 분석을 처음부터 다시 시작
+	CommitHash string `json:"commitHash"`
+
+	ConsecutiveSuccesses int `json:"consecutiveSuccesses"`
+	ConsecutiveFailures  int `json:"consecutiveFailures"`
+
+	// +optional
+	LastCheckedAt *metav1.Time `json:"lastCheckedAt,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	Results []AnalysisRuleResult `json:"results,omitempty"`
+}
+
+type AnalysisRuleResult struct {
+	Name   string `json:"name"`
+	Value  string `json:"value,omitempty"`
+	Max    string `json:"max"`
+	Passed bool   `json:"passed"`
+}
+
+type RolloutStatus struct {
+	Phase RolloutPhase `json:"phase"`
+
+	// 현재 단계 인덱스 (Promoted 인 경우 len(Steps))
+	CurrentStep int `json:"currentStep"`
+
+	// 현재 단계 시작 시각
+	StepStartedAt metav1.Time `json:"stepStartedAt"`
+
+	// 커밋 해시나 단계가 바뀌면 처음부터 다시 시작하기 위한 식별자
+	Revision string `json:"revision"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Progressing",type="string",JSONPath=".status.conditions[?(@.type==\"Progressing\")].status"
+// +kubebuilder:printcolumn:name="Hash",type="string",JSONPath=".status.lastAppliedHash",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // IstioRoute is the Schema for the istioroutes API
 type IstioRoute struct {
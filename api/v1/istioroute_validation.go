@@ -0,0 +1,598 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ValidateSpec 클러스터 조회 없이 판단 가능한 spec 검증. 웹훅이 꺼진 환경에서도 컨트롤러가 같은 규칙으로 거부
+func (in *IstioRoute) ValidateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	for i := range in.Spec.Services {
+		allErrs = append(allErrs, in.ValidateService(i)...)
+	}
+	return append(allErrs, in.ValidateGateway()...)
+}
+
+// ValidateService spec.services[i] 검증. 의존성 확인을 위해 다른 서비스 목록도 사용
+func (in *IstioRoute) ValidateService(i int) field.ErrorList {
+	var allErrs field.ErrorList
+	path := field.NewPath("spec").Child("services").Index(i)
+	svc := in.Spec.Services[i]
+
+	// name/namespace 별 첫 번째 서비스 위치. 중복 검사와 의존성 확인용
+	services := make(map[string]int)
+	for j, other := range in.Spec.Services {
+		otherKey := serviceKey(other.Namespace, other.Name)
+		if _, ok := services[otherKey]; !ok {
+			services[otherKey] = j
+		}
+	}
+	key := serviceKey(svc.Namespace, svc.Name)
+	if services[key] != i {
+		allErrs = append(allErrs, field.Duplicate(path, key))
+	}
+
+	return append(allErrs, validateService(svc, services, path)...)
+}
+
+// ValidateServiceSubsets spec.services[i] 의 다크니스 릴리즈 커밋 해시에 해당하는 version 라벨의 Deployment 존재 여부 확인.
+// 클러스터 조회가 필요하므로 ValidateSpec 과 분리
+func (in *IstioRoute) ValidateServiceSubsets(ctx context.Context, c client.Reader, i int) field.ErrorList {
+	svc := in.Spec.Services[i]
+	if len(svc.DarknessReleases) == 0 {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	path := field.NewPath("spec").Child("services").Index(i).Child("darknessReleases")
+
+	deployList := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployList, client.InNamespace(svc.Namespace)); err != nil {
+		return append(allErrs, field.InternalError(path,
+			fmt.Errorf("failed to list deployments in %s: %w", svc.Namespace, err)))
+	}
+
+	versions := make(map[string]struct{})
+	for _, deploy := range deployList.Items {
+		if v, ok := deploy.Spec.Template.Labels["version"]; ok {
+			versions[v] = struct{}{}
+		}
+	}
+
+	for j, dr := range svc.DarknessReleases {
+		if _, ok := versions[dr.CommitHash]; !ok {
+			allErrs = append(allErrs, field.Invalid(path.Index(j).Child("commitHash"), dr.CommitHash,
+				fmt.Sprintf("no Deployment in namespace %q has pod label version=%s", svc.Namespace, dr.CommitHash)))
+		}
+	}
+
+	return allErrs
+}
+
+// ValidateGateway spec.gateway 검증
+func (in *IstioRoute) ValidateGateway() field.ErrorList {
+	if in.Spec.Gateway == nil {
+		return nil
+	}
+	return validateGateway(in.Spec.Gateway, field.NewPath("spec").Child("gateway"))
+}
+
+// validateGateway 포트/리스너 이름 중복과 TLS 설정 검사
+func validateGateway(gw *GatewayConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	ports := make(map[int32]struct{})
+	names := make(map[string]struct{})
+	for j, server := range gw.Servers {
+		serverPath := path.Child("servers").Index(j)
+
+		if _, dup := ports[server.Port]; dup {
+			allErrs = append(allErrs, field.Duplicate(serverPath.Child("port"), server.Port))
+		}
+		ports[server.Port] = struct{}{}
+		if server.Name != "" {
+			if _, dup := names[server.Name]; dup {
+				allErrs = append(allErrs, field.Duplicate(serverPath.Child("name"), server.Name))
+			}
+			names[server.Name] = struct{}{}
+		}
+
+		if server.Protocol == GatewayHTTPS {
+			if server.CredentialName == "" {
+				allErrs = append(allErrs, field.Required(serverPath.Child("credentialName"), "HTTPS servers require a TLS secret"))
+			}
+			if server.HTTPSRedirect {
+				allErrs = append(allErrs, field.Forbidden(serverPath.Child("httpsRedirect"), "only HTTP servers can redirect to HTTPS"))
+			}
+		} else if server.CredentialName != "" {
+			allErrs = append(allErrs, field.Forbidden(serverPath.Child("credentialName"), "only HTTPS servers use a TLS secret"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateService(svc ServiceConfig, services map[string]int, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch svc.Type {
+	case CanaryType, StickyCanaryType:
+		if len(svc.Weights) > 0 {
+			allErrs = append(allErrs, validateWeights(svc, path)...)
+			break
+		}
+		if svc.Ratio == nil {
+			allErrs = append(allErrs, field.Required(path.Child("ratio"),
+				fmt.Sprintf("%s requires a ratio", svc.Type)))
+		}
+		if len(svc.CommitHashes) != 2 {
+			allErrs = append(allErrs, field.Invalid(path.Child("commitHashes"), svc.CommitHashes,
+				fmt.Sprintf("%s requires exactly two commit hashes (stable, canary) or weights", svc.Type)))
+		}
+	case StandardType:
+		if len(svc.CommitHashes) == 0 {
+			allErrs = append(allErrs, field.Required(path.Child("commitHashes"),
+				"StandardType requires at least one commit hash"))
+		}
+	case MirrorType:
+		if len(svc.CommitHashes) != 2 {
+			allErrs = append(allErrs, field.Invalid(path.Child("commitHashes"), svc.CommitHashes,
+				"MirrorType requires exactly two commit hashes (stable, shadow)"))
+		}
+		if svc.Ratio != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("ratio"), "MirrorType never sends live responses from the shadow commit"))
+		}
+	case BlueGreenType:
+		if len(svc.CommitHashes) != 2 {
+			allErrs = append(allErrs, field.Invalid(path.Child("commitHashes"), svc.CommitHashes,
+				"BlueGreenType requires exactly two commit hashes"))
+		}
+		if svc.Ratio != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("ratio"), "BlueGreenType switches all traffic at once"))
+		}
+		if svc.BlueGreen != nil && svc.BlueGreen.ActiveCommitHash != "" {
+			if !slices.Contains(svc.CommitHashes, svc.BlueGreen.ActiveCommitHash) {
+				allErrs = append(allErrs, field.NotSupported(path.Child("blueGreen", "activeCommitHash"), svc.BlueGreen.ActiveCommitHash, svc.CommitHashes))
+			}
+		}
+	case ABTestType:
+		if len(svc.CommitHashes) == 0 {
+			allErrs = append(allErrs, field.Required(path.Child("commitHashes"), "at least one commit hash is required"))
+		}
+		if svc.Ratio != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("ratio"), "ABTestType routes by request attributes, not by ratio"))
+		}
+		if svc.ABTest == nil {
+			allErrs = append(allErrs, field.Required(path.Child("abTest"), "abTest rules are required for ABTestType"))
+		} else {
+			allErrs = append(allErrs, validateABTest(svc, path.Child("abTest"))...)
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("type"), svc.Type,
+			[]string{string(StandardType), string(CanaryType), string(StickyCanaryType),
+				string(MirrorType), string(BlueGreenType), string(ABTestType)}))
+	}
+
+	if svc.Ingress != nil {
+		allErrs = append(allErrs, validateIngress(svc.Ingress, path.Child("ingress"))...)
+	}
+
+	if svc.FallbackCommitHash != "" {
+		allErrs = append(allErrs, validateFallback(svc, path.Child("fallbackCommitHash"))...)
+	}
+
+	if svc.RoutingMode == WeightedRoutingMode {
+		if svc.Type != CanaryType {
+			allErrs = append(allErrs, field.Forbidden(path.Child("routingMode"), "Weighted routing is only supported for CanaryType"))
+		}
+		// 의존성 헤더는 게이트웨이 Lua 가 붙이므로 함께 사용할 수 없음
+		if len(svc.Dependencies) > 0 {
+			allErrs = append(allErrs, field.Forbidden(path.Child("routingMode"), "Weighted routing cannot be combined with dependencies"))
+		}
+	}
+
+	if len(svc.Weights) > 0 && svc.Type != CanaryType && svc.Type != StickyCanaryType {
+		allErrs = append(allErrs, field.Forbidden(path.Child("weights"), "weights are only supported for CanaryType and StickyCanaryType"))
+	}
+
+	if svc.ABTest != nil && svc.Type != ABTestType {
+		allErrs = append(allErrs, field.Forbidden(path.Child("abTest"), "abTest is only supported for ABTestType"))
+	}
+
+	if svc.BlueGreen != nil && svc.Type != BlueGreenType {
+		allErrs = append(allErrs, field.Forbidden(path.Child("blueGreen"), "blueGreen is only supported for BlueGreenType"))
+	}
+
+	if svc.Mirror != nil && svc.Type != MirrorType {
+		allErrs = append(allErrs, field.Forbidden(path.Child("mirror"), "mirror is only supported for MirrorType"))
+	}
+
+	if len(svc.Steps) > 0 && svc.Type != CanaryType && svc.Type != StickyCanaryType {
+		allErrs = append(allErrs, field.Forbidden(path.Child("steps"),
+			"steps are only supported for CanaryType and StickyCanaryType"))
+	}
+	for j, step := range svc.Steps {
+		if _, err := time.ParseDuration(step.Duration); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("steps").Index(j).Child("duration"), step.Duration, err.Error()))
+		}
+	}
+
+	if svc.Analysis != nil {
+		allErrs = append(allErrs, validateAnalysis(svc, path.Child("analysis"))...)
+	}
+
+	if svc.OutlierDetection != nil {
+		allErrs = append(allErrs, validateOutlierDetection(svc.OutlierDetection, path.Child("outlierDetection"))...)
+	}
+
+	if svc.Timeout != "" {
+		if _, err := time.ParseDuration(svc.Timeout); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("timeout"), svc.Timeout, err.Error()))
+		}
+	}
+	if svc.Retries != nil && svc.Retries.PerTryTimeout != "" {
+		if _, err := time.ParseDuration(svc.Retries.PerTryTimeout); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("retries", "perTryTimeout"), svc.Retries.PerTryTimeout, err.Error()))
+		}
+	}
+	if svc.Fault != nil {
+		allErrs = append(allErrs, validateFault(svc, path.Child("fault"))...)
+	}
+
+	if svc.TrafficPolicy != nil {
+		allErrs = append(allErrs, validateTrafficPolicy(svc, path.Child("trafficPolicy"))...)
+	}
+
+	if svc.StickySession != nil {
+		allErrs = append(allErrs, validateStickySession(svc, path.Child("stickySession"))...)
+	}
+
+	for j, dr := range svc.DarknessReleases {
+		drPath := path.Child("darknessReleases").Index(j)
+		if len(dr.IPs) == 0 && len(dr.Headers) == 0 && len(dr.Cookies) == 0 && len(dr.UserIDs) == 0 {
+			allErrs = append(allErrs, field.Required(drPath, "at least one of ips, headers, cookies or userIds is required"))
+		}
+		for k, ip := range dr.IPs {
+			if !isIPOrCIDR(ip) {
+				allErrs = append(allErrs, field.Invalid(drPath.Child("ips").Index(k), ip, "must be a valid IP address or CIDR"))
+			}
+		}
+	}
+
+	for j, dep := range svc.Dependencies {
+		depPath := path.Child("dependencies").Index(j)
+		key := serviceKey(dep.Namespace, dep.Name)
+		if _, ok := services[key]; !ok {
+			allErrs = append(allErrs, field.NotFound(depPath, key))
+		}
+	}
+
+	return allErrs
+}
+
+func validateIngress(ingress *IngressConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for j, host := range ingress.Hosts {
+		if host == "" {
+			allErrs = append(allErrs, field.Required(path.Child("hosts").Index(j), "host must not be empty"))
+		}
+	}
+	if ingress.PathType == IngressPathRegex {
+		if _, err := regexp.Compile(ingress.Path); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("path"), ingress.Path, err.Error()))
+		}
+	} else if !strings.HasPrefix(ingress.Path, "/") {
+		allErrs = append(allErrs, field.Invalid(path.Child("path"), ingress.Path, "must start with /"))
+	}
+	if ingress.Rewrite != nil && *ingress.Rewrite != "" && !strings.HasPrefix(*ingress.Rewrite, "/") {
+		allErrs = append(allErrs, field.Invalid(path.Child("rewrite"), *ingress.Rewrite, "must start with / or be empty to disable rewriting"))
+	}
+
+	return allErrs
+}
+
+// validateFallback 헤더 기반 라우팅을 쓰는 타입에서만 의미가 있으며 다크니스 릴리즈 커밋은 사용할 수 없음
+func validateFallback(svc ServiceConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch {
+	case svc.Type != StandardType && svc.Type != CanaryType && svc.Type != StickyCanaryType:
+		allErrs = append(allErrs, field.Forbidden(path, fmt.Sprintf("fallbackCommitHash is not supported for %s", svc.Type)))
+	case svc.RoutingMode == WeightedRoutingMode:
+		allErrs = append(allErrs, field.Forbidden(path, "Weighted routing has no header-less fallback"))
+	case !slices.Contains(svc.CommitHashes, svc.FallbackCommitHash):
+		allErrs = append(allErrs, field.NotSupported(path, svc.FallbackCommitHash, svc.CommitHashes))
+	}
+	for _, dr := range svc.DarknessReleases {
+		if dr.CommitHash == svc.FallbackCommitHash {
+			allErrs = append(allErrs, field.Forbidden(path, "must not be a darkness release commit"))
+			break
+		}
+	}
+
+	return allErrs
+}
+
+// validateWeights 모든 커밋에 한 번씩 비율을 지정하고 합계는 100
+func validateWeights(svc ServiceConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	weightsPath := path.Child("weights")
+
+	if svc.Ratio != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("ratio"), "ratio and weights are mutually exclusive"))
+	}
+	// 단계별 롤아웃과 분석은 두 커밋 형식의 ratio 를 조정
+	if len(svc.Steps) > 0 {
+		allErrs = append(allErrs, field.Forbidden(path.Child("steps"), "steps cannot be combined with weights"))
+	}
+	if svc.Analysis != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("analysis"), "analysis cannot be combined with weights"))
+	}
+	if len(svc.CommitHashes) < 2 {
+		allErrs = append(allErrs, field.Invalid(path.Child("commitHashes"), svc.CommitHashes,
+			"weights require at least two commit hashes"))
+	}
+
+	total := 0
+	seen := make(map[string]struct{})
+	for j, w := range svc.Weights {
+		if !slices.Contains(svc.CommitHashes, w.CommitHash) {
+			allErrs = append(allErrs, field.NotSupported(weightsPath.Index(j).Child("commitHash"), w.CommitHash, svc.CommitHashes))
+		}
+		if _, dup := seen[w.CommitHash]; dup {
+			allErrs = append(allErrs, field.Duplicate(weightsPath.Index(j).Child("commitHash"), w.CommitHash))
+		}
+		seen[w.CommitHash] = struct{}{}
+		total += w.Weight
+	}
+	for _, hash := range svc.CommitHashes {
+		if _, ok := seen[hash]; !ok {
+			allErrs = append(allErrs, field.Required(weightsPath, fmt.Sprintf("commit hash %q has no weight", hash)))
+		}
+	}
+	if total != 100 {
+		allErrs = append(allErrs, field.Invalid(weightsPath, total, "weights must sum to 100"))
+	}
+
+	return allErrs
+}
+
+func validateABTest(svc ServiceConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if svc.ABTest.DefaultCommitHash != "" && !slices.Contains(svc.CommitHashes, svc.ABTest.DefaultCommitHash) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("defaultCommitHash"), svc.ABTest.DefaultCommitHash, svc.CommitHashes))
+	}
+	if len(svc.ABTest.Rules) == 0 {
+		allErrs = append(allErrs, field.Required(path.Child("rules"), "at least one rule is required"))
+	}
+	for j, rule := range svc.ABTest.Rules {
+		rulePath := path.Child("rules").Index(j)
+		if !slices.Contains(svc.CommitHashes, rule.CommitHash) {
+			allErrs = append(allErrs, field.NotSupported(rulePath.Child("commitHash"), rule.CommitHash, svc.CommitHashes))
+		}
+		if len(rule.Headers) == 0 && len(rule.QueryParams) == 0 && rule.UserAgent == "" {
+			allErrs = append(allErrs, field.Required(rulePath, "at least one of headers, queryParams or userAgent is required"))
+		}
+		if rule.UserAgent != "" {
+			if _, err := regexp.Compile(rule.UserAgent); err != nil {
+				allErrs = append(allErrs, field.Invalid(rulePath.Child("userAgent"), rule.UserAgent, err.Error()))
+			}
+		}
+		for k, h := range rule.Headers {
+			allErrs = append(allErrs, validateValueMatch(h, rulePath.Child("headers").Index(k))...)
+		}
+		for k, q := range rule.QueryParams {
+			allErrs = append(allErrs, validateValueMatch(q, rulePath.Child("queryParams").Index(k))...)
+		}
+	}
+
+	return allErrs
+}
+
+// validateValueMatch exact 와 regex 중 하나만 허용
+func validateValueMatch(m ValueMatch, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch {
+	case m.Exact != "" && m.Regex != "":
+		allErrs = append(allErrs, field.Forbidden(path.Child("regex"), "exact and regex are mutually exclusive"))
+	case m.Exact == "" && m.Regex == "":
+		allErrs = append(allErrs, field.Required(path, "one of exact or regex is required"))
+	case m.Regex != "":
+		if _, err := regexp.Compile(m.Regex); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("regex"), m.Regex, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+func validateAnalysis(svc ServiceConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if svc.Type != CanaryType && svc.Type != StickyCanaryType {
+		allErrs = append(allErrs, field.Forbidden(path, "analysis is only supported for CanaryType and StickyCanaryType"))
+	}
+	if svc.Analysis.Interval != "" {
+		if _, err := time.ParseDuration(svc.Analysis.Interval); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("interval"), svc.Analysis.Interval, err.Error()))
+		}
+	}
+	if len(svc.Analysis.Rules) == 0 {
+		allErrs = append(allErrs, field.Required(path.Child("rules"), "at least one rule is required"))
+	}
+	for j, rule := range svc.Analysis.Rules {
+		rulePath := path.Child("rules").Index(j)
+		if _, err := strconv.ParseFloat(rule.Max, 64); err != nil {
+			allErrs = append(allErrs, field.Invalid(rulePath.Child("max"), rule.Max, "must be a number"))
+		}
+		if rule.Metric == CustomMetric && rule.Query == "" {
+			allErrs = append(allErrs, field.Required(rulePath.Child("query"), "query is required for Custom metric"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateOutlierDetection(od *OutlierDetection, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if od.Interval != "" {
+		if _, err := time.ParseDuration(od.Interval); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("interval"), od.Interval, err.Error()))
+		}
+	}
+	if od.BaseEjectionTime != "" {
+		if _, err := time.ParseDuration(od.BaseEjectionTime); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("baseEjectionTime"), od.BaseEjectionTime, err.Error()))
+		}
+	}
+	if od.ConsecutiveLocalOriginFailures > 0 && !od.SplitExternalLocalOriginErrors {
+		allErrs = append(allErrs, field.Forbidden(path.Child("consecutiveLocalOriginFailures"),
+			"requires splitExternalLocalOriginErrors to be true"))
+	}
+
+	return allErrs
+}
+
+func validateFault(svc ServiceConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	fault := svc.Fault
+
+	if fault.Delay == nil && fault.Abort == nil {
+		allErrs = append(allErrs, field.Required(path, "at least one of delay or abort is required"))
+	}
+	if fault.Delay != nil {
+		if _, err := time.ParseDuration(fault.Delay.FixedDelay); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("delay", "fixedDelay"), fault.Delay.FixedDelay, err.Error()))
+		}
+	}
+	if fault.CommitHash != "" {
+		if _, ok := serviceCommitHashes(svc)[fault.CommitHash]; !ok {
+			allErrs = append(allErrs, field.NotFound(path.Child("commitHash"), fault.CommitHash))
+		}
+		// 가중치 라우팅은 버전 헤더가 없어 특정 커밋으로 가는 요청만 골라낼 수 없음
+		if svc.RoutingMode == WeightedRoutingMode {
+			allErrs = append(allErrs, field.Forbidden(path.Child("commitHash"), "commitHash cannot be combined with Weighted routing"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateTrafficPolicy(svc ServiceConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	policy := svc.TrafficPolicy
+
+	// StickyCanary 는 세션 고정을 위해 ConsistentHash 로드밸런서를 사용
+	if svc.Type == StickyCanaryType && policy.LoadBalancer != "" {
+		allErrs = append(allErrs, field.Forbidden(path.Child("loadBalancer"), "StickyCanaryType always uses consistent hashing"))
+	}
+	allErrs = append(allErrs, validateConnectionPool(policy.ConnectionPool, path.Child("connectionPool"))...)
+
+	hashes := serviceCommitHashes(svc)
+
+	seen := make(map[string]struct{})
+	for j, subset := range policy.Subsets {
+		subsetPath := path.Child("subsets").Index(j)
+		if _, ok := hashes[subset.CommitHash]; !ok {
+			allErrs = append(allErrs, field.NotFound(subsetPath.Child("commitHash"), subset.CommitHash))
+		}
+		if _, dup := seen[subset.CommitHash]; dup {
+			allErrs = append(allErrs, field.Duplicate(subsetPath.Child("commitHash"), subset.CommitHash))
+		}
+		seen[subset.CommitHash] = struct{}{}
+
+		if svc.Type == StickyCanaryType && subset.LoadBalancer != "" {
+			allErrs = append(allErrs, field.Forbidden(subsetPath.Child("loadBalancer"), "StickyCanaryType always uses consistent hashing"))
+		}
+		allErrs = append(allErrs, validateConnectionPool(subset.ConnectionPool, subsetPath.Child("connectionPool"))...)
+	}
+
+	return allErrs
+}
+
+func validateConnectionPool(pool *ConnectionPool, path *field.Path) field.ErrorList {
+	if pool == nil || pool.IdleTimeout == "" {
+		return nil
+	}
+	if _, err := time.ParseDuration(pool.IdleTimeout); err != nil {
+		return field.ErrorList{field.Invalid(path.Child("idleTimeout"), pool.IdleTimeout, err.Error())}
+	}
+	return nil
+}
+
+func validateStickySession(svc ServiceConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	session := svc.StickySession
+
+	if svc.Type != StickyCanaryType {
+		allErrs = append(allErrs, field.Forbidden(path, "stickySession is only supported for StickyCanaryType"))
+	}
+	switch session.Source {
+	case StickyHeaderSource, StickyCookieSource, StickyJWTClaimSource:
+		if session.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("name"),
+				fmt.Sprintf("name is required for %s source", session.Source)))
+		}
+	case StickyClientIPSource:
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("source"), session.Source,
+			[]string{string(StickyHeaderSource), string(StickyCookieSource),
+				string(StickyJWTClaimSource), string(StickyClientIPSource)}))
+	}
+
+	return allErrs
+}
+
+func isIPOrCIDR(value string) bool {
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return true
+	}
+	return net.ParseIP(value) != nil
+}
+
+// serviceCommitHashes DestinationRule 서브셋으로 생성되는 커밋 해시
+func serviceCommitHashes(svc ServiceConfig) map[string]struct{} {
+	hashes := make(map[string]struct{})
+	for _, hash := range svc.CommitHashes {
+		hashes[hash] = struct{}{}
+	}
+	for _, dr := range svc.DarknessReleases {
+		hashes[dr.CommitHash] = struct{}{}
+	}
+	return hashes
+}
+
+func serviceKey(namespace, name string) string {
+	return namespace + "/" + name
+}
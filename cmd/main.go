@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"github.com/MeshManager/MeshManagerAgent/external/desired_state_service"
+	"github.com/MeshManager/MeshManagerAgent/external/env_service"
 	"github.com/MeshManager/MeshManagerAgent/external/metrics_service"
 	"os"
 	"time"
@@ -30,6 +31,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -40,9 +42,13 @@ import (
 
 	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
 	"github.com/MeshManager/MeshManagerAgent/internal/controller"
+	"github.com/MeshManager/MeshManagerAgent/internal/controller/analysis"
+	generator "github.com/MeshManager/MeshManagerAgent/internal/controller/generators"
+	webhookmeshmanagerv1 "github.com/MeshManager/MeshManagerAgent/internal/webhook/v1"
 	// +kubebuilder:scaffold:imports
 
 	// Istio networking 타입들 추가
+	istionetworkingv1 "istio.io/client-go/pkg/apis/networking/v1"
 	istionetworkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 
 	istionetworkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
@@ -62,6 +68,8 @@ func init() {
 	utilruntime.Must(istionetworkingv1alpha3.AddToScheme(scheme))
 
 	utilruntime.Must(istionetworkingv1beta1.AddToScheme(scheme))
+
+	utilruntime.Must(istionetworkingv1.AddToScheme(scheme))
 }
 
 func main() {
@@ -156,13 +164,41 @@ func main() {
 		os.Exit(1)
 	}
 
+	// canary 분석용 Prometheus 주소 (IstioRoute 에서 서비스별로 덮어쓸 수 있음)
+	prometheusURL, err := env_service.GetPrometheusUrl()
+	if err != nil {
+		setupLog.Info("canary analysis will only use per-service addresses", "reason", err.Error())
+	}
+
+	// 클러스터가 제공하는 가장 최신 networking.istio.io 버전으로 생성/감시
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create discovery client")
+		os.Exit(1)
+	}
+	networkingVersion, err := generator.DiscoverNetworkingVersion(discoveryClient)
+	if err != nil {
+		setupLog.Error(err, "unable to discover Istio networking API version")
+		os.Exit(1)
+	}
+	setupLog.Info("using Istio networking API", "version", networkingVersion)
+
 	if err = (&controller.IstioRouteReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		Analyzer:          analysis.New(prometheusURL),
+		NetworkingVersion: networkingVersion,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "IstioRoute")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookmeshmanagerv1.SetupIstioRouteWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "IstioRoute")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -45,6 +45,19 @@ func GetDesiredStateUrl() (string, error) {
 	return desiredStateUrl, nil
 }
 
+func GetPrometheusUrl() (string, error) {
+	prometheusUrl := os.Getenv("PROMETHEUS_URL")
+	if prometheusUrl == "" {
+		return "", fmt.Errorf("PROMETHEUS_URL 환경변수가 설정되지 않았거나 비어 있습니다")
+	}
+
+	if !strings.HasPrefix(prometheusUrl, "http") {
+		return "", fmt.Errorf("PROMETHEUS_URL이 http 또는 https가 아닙니다")
+	}
+
+	return prometheusUrl, nil
+}
+
 func GetAgentUuid() (string, error) {
 	agentUuid := os.Getenv("UUID")
 	if agentUuid == "" {
@@ -0,0 +1,191 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+)
+
+// Istio 표준 메트릭 기반 쿼리. destination_version 은 파드의 version 라벨(=커밋 해시)
+const (
+	// 5xx 가 한 번도 없으면 분자 시리즈가 없으므로 0 으로 대체. 트래픽 자체가 없으면 분모가 비어 결과 없음
+	errorRateQuery = `(sum(rate(istio_requests_total{reporter="destination",destination_service_name="{{service}}",destination_service_namespace="{{namespace}}",destination_version="{{version}}",response_code=~"5.."}[{{interval}}])) or vector(0))` +
+		` / sum(rate(istio_requests_total{reporter="destination",destination_service_name="{{service}}",destination_service_namespace="{{namespace}}",destination_version="{{version}}"}[{{interval}}]))`
+	latencyP99Query = `histogram_quantile(0.99, sum(rate(istio_request_duration_milliseconds_bucket{reporter="destination",destination_service_name="{{service}}",destination_service_namespace="{{namespace}}",destination_version="{{version}}"}[{{interval}}])) by (le))`
+)
+
+// Analyzer Prometheus 호환 API 로 분석 규칙을 평가
+type Analyzer struct {
+	// DefaultAddress ServiceConfig.Analysis.Address 가 비어 있을 때 사용
+	DefaultAddress string
+}
+
+func New(defaultAddress string) *Analyzer {
+	return &Analyzer{DefaultAddress: defaultAddress}
+}
+
+// Evaluate 신규 커밋(version)에 대해 모든 규칙을 한 번 평가.
+// 트래픽이 없어 값이 없는 규칙은 Value 가 비어 있고 Passed=false, noData=true
+func (a *Analyzer) Evaluate(ctx context.Context, svc meshmanagerv1.ServiceConfig, version string) (results []meshmanagerv1.AnalysisRuleResult, noData bool, err error) {
+	cfg := svc.Analysis
+	address := cfg.Address
+	if address == "" {
+		address = a.DefaultAddress
+	}
+	if address == "" {
+		return nil, false, fmt.Errorf("prometheus address is not configured")
+	}
+
+	client, err := api.NewClient(api.Config{Address: address})
+	if err != nil {
+		return nil, false, fmt.Errorf("prometheus client 생성 실패: %w", err)
+	}
+	promAPI := promv1.NewAPI(client)
+
+	replacer := strings.NewReplacer(
+		"{{service}}", svc.Name,
+		"{{namespace}}", svc.Namespace,
+		"{{version}}", version,
+		"{{interval}}", cfg.Interval,
+	)
+
+	for _, rule := range cfg.Rules {
+		max, err := strconv.ParseFloat(rule.Max, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("rule %s: invalid max %q: %w", rule.Name, rule.Max, err)
+		}
+
+		query, err := ruleQuery(rule)
+		if err != nil {
+			return nil, false, err
+		}
+
+		value, found, err := queryScalar(ctx, promAPI, replacer.Replace(query))
+		if err != nil {
+			return nil, false, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+
+		result := meshmanagerv1.AnalysisRuleResult{Name: rule.Name, Max: rule.Max}
+		if !found {
+			noData = true
+		} else {
+			result.Value = strconv.FormatFloat(value, 'f', -1, 64)
+			result.Passed = value <= max
+		}
+		results = append(results, result)
+	}
+
+	return results, noData, nil
+}
+
+func ruleQuery(rule meshmanagerv1.AnalysisRule) (string, error) {
+	switch rule.Metric {
+	case meshmanagerv1.ErrorRateMetric:
+		return errorRateQuery, nil
+	case meshmanagerv1.LatencyP99Metric:
+		return latencyP99Query, nil
+	case meshmanagerv1.CustomMetric:
+		if rule.Query == "" {
+			return "", fmt.Errorf("rule %s: query is required for Custom metric", rule.Name)
+		}
+		return rule.Query, nil
+	default:
+		return "", fmt.Errorf("rule %s: unsupported metric %q", rule.Name, rule.Metric)
+	}
+}
+
+// queryScalar instant query 결과의 첫 번째 값. 결과가 없거나 NaN 이면 found=false
+func queryScalar(ctx context.Context, promAPI promv1.API, query string) (float64, bool, error) {
+	value, _, err := promAPI.Query(ctx, query, time.Now())
+	if err != nil {
+		return 0, false, err
+	}
+
+	var v float64
+	switch typed := value.(type) {
+	case model.Vector:
+		if len(typed) == 0 {
+			return 0, false, nil
+		}
+		v = float64(typed[0].Value)
+	case *model.Scalar:
+		v = float64(typed.Value)
+	default:
+		return 0, false, fmt.Errorf("unsupported result type %s", value.Type())
+	}
+
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false, nil
+	}
+	return v, true, nil
+}
+
+// Next 이전 분석 상태와 이번 평가 결과로 다음 상태를 계산.
+// promoteOnSuccess 가 false 면(Steps 사용 시) 성공해도 Running 유지
+func Next(prev *meshmanagerv1.AnalysisStatus, cfg *meshmanagerv1.Analysis, version string,
+	results []meshmanagerv1.AnalysisRuleResult, noData bool, evalErr error, promoteOnSuccess bool, now time.Time) *meshmanagerv1.AnalysisStatus {
+
+	status := &meshmanagerv1.AnalysisStatus{
+		Phase:      meshmanagerv1.AnalysisRunning,
+		CommitHash: version,
+	}
+	if prev != nil && prev.CommitHash == version {
+		status = prev.DeepCopy()
+	}
+	if status.Phase != meshmanagerv1.AnalysisRunning {
+		return status
+	}
+
+	checkedAt := metav1.NewTime(now)
+	status.LastCheckedAt = &checkedAt
+
+	// 조회 실패/트래픽 없음은 판단 보류
+	switch {
+	case evalErr != nil:
+		status.Message = fmt.Sprintf("analysis inconclusive: %v", evalErr)
+		return status
+	case noData:
+		status.Results = results
+		status.Message = "analysis inconclusive: no traffic data for the new commit"
+		return status
+	}
+
+	status.Results = results
+
+	var failed []string
+	for _, r := range results {
+		if !r.Passed {
+			failed = append(failed, fmt.Sprintf("%s=%s (max %s)", r.Name, r.Value, r.Max))
+		}
+	}
+
+	if len(failed) > 0 {
+		status.ConsecutiveSuccesses = 0
+		status.ConsecutiveFailures++
+		status.Message = "threshold exceeded: " + strings.Join(failed, ", ")
+		if status.ConsecutiveFailures > cfg.FailureLimit {
+			status.Phase = meshmanagerv1.AnalysisAborted
+			status.Message = "rolled back to stable commit, " + status.Message
+		}
+		return status
+	}
+
+	status.ConsecutiveFailures = 0
+	status.ConsecutiveSuccesses++
+	status.Message = "all rules passed"
+	if promoteOnSuccess && status.ConsecutiveSuccesses >= cfg.SuccessThreshold {
+		status.Phase = meshmanagerv1.AnalysisPromoted
+		status.Message = "promoted new commit after consecutive successful analyses"
+	}
+	return status
+}
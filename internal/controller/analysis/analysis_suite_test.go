@@ -0,0 +1,14 @@
+package analysis
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAnalysis(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Analysis Suite")
+}
@@ -0,0 +1,153 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+)
+
+// fakePrometheus /api/v1/query 요청에 대해 쿼리 내용으로 값을 골라 응답
+func fakePrometheus(values map[string]string, queries *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Expect(r.ParseForm()).To(Succeed())
+		query := r.Form.Get("query")
+		*queries = append(*queries, query)
+
+		result := "[]"
+		for key, value := range values {
+			if strings.Contains(query, key) {
+				result = fmt.Sprintf(`[{"metric":{},"value":[%d,"%s"]}]`, time.Now().Unix(), value)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":%s}}`, result)
+	}))
+}
+
+var _ = Describe("Canary analysis", func() {
+	var (
+		ctx     context.Context
+		svc     meshmanagerv1.ServiceConfig
+		queries []string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		queries = nil
+		svc = meshmanagerv1.ServiceConfig{
+			Name:         "user",
+			Namespace:    "shop",
+			Type:         meshmanagerv1.CanaryType,
+			CommitHashes: []string{"stable", "canary"},
+			Analysis: &meshmanagerv1.Analysis{
+				Interval:         "1m",
+				SuccessThreshold: 2,
+				Rules: []meshmanagerv1.AnalysisRule{
+					{Name: "errors", Metric: meshmanagerv1.ErrorRateMetric, Max: "0.05"},
+					{Name: "latency", Metric: meshmanagerv1.LatencyP99Metric, Max: "300"},
+				},
+			},
+		}
+	})
+
+	It("scopes queries to the canary subset and compares against thresholds", func() {
+		server := fakePrometheus(map[string]string{
+			`response_code=~"5.."`: "0.01",
+			"histogram_quantile":   "450",
+		}, &queries)
+		defer server.Close()
+
+		results, noData, err := New(server.URL).Evaluate(ctx, svc, "canary")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(noData).To(BeFalse())
+		Expect(queries).To(HaveLen(2))
+		for _, q := range queries {
+			Expect(q).To(ContainSubstring(`destination_version="canary"`))
+			Expect(q).To(ContainSubstring(`destination_service_namespace="shop"`))
+			Expect(q).To(ContainSubstring("[1m]"))
+		}
+
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].Passed).To(BeTrue())
+		Expect(results[1].Passed).To(BeFalse())
+		Expect(results[1].Value).To(Equal("450"))
+	})
+
+	It("reports no data when the canary has no traffic", func() {
+		server := fakePrometheus(map[string]string{}, &queries)
+		defer server.Close()
+
+		_, noData, err := New(server.URL).Evaluate(ctx, svc, "canary")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(noData).To(BeTrue())
+	})
+
+	It("promotes a healthy canary that has never returned a 5xx", func() {
+		// 5xx 시리즈가 없으면 분자가 빈 벡터가 되므로 vector(0) 대체가 없을 때만 빈 결과 응답
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.ParseForm()).To(Succeed())
+			query := r.Form.Get("query")
+
+			result := fmt.Sprintf(`[{"metric":{},"value":[%d,"120"]}]`, time.Now().Unix())
+			if strings.Contains(query, `response_code=~"5.."`) {
+				result = "[]"
+				if strings.Contains(query, "or vector(0)") {
+					result = fmt.Sprintf(`[{"metric":{},"value":[%d,"0"]}]`, time.Now().Unix())
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":%s}}`, result)
+		}))
+		defer server.Close()
+
+		analyzer := New(server.URL)
+		var status *meshmanagerv1.AnalysisStatus
+		for i := 0; i < svc.Analysis.SuccessThreshold; i++ {
+			results, noData, err := analyzer.Evaluate(ctx, svc, "canary")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(noData).To(BeFalse())
+			Expect(results[0].Value).To(Equal("0"))
+			status = Next(status, svc.Analysis, "canary", results, noData, nil, true, time.Now())
+		}
+		Expect(status.Phase).To(Equal(meshmanagerv1.AnalysisPromoted))
+	})
+
+	It("promotes after consecutive successes and aborts past the failure limit", func() {
+		now := time.Now()
+		passed := []meshmanagerv1.AnalysisRuleResult{{Name: "errors", Value: "0", Max: "0.05", Passed: true}}
+		failed := []meshmanagerv1.AnalysisRuleResult{{Name: "errors", Value: "0.5", Max: "0.05", Passed: false}}
+
+		status := Next(nil, svc.Analysis, "canary", passed, false, nil, true, now)
+		Expect(status.Phase).To(Equal(meshmanagerv1.AnalysisRunning))
+		status = Next(status, svc.Analysis, "canary", passed, false, nil, true, now)
+		Expect(status.Phase).To(Equal(meshmanagerv1.AnalysisPromoted))
+
+		svc.Analysis.FailureLimit = 1
+		status = Next(nil, svc.Analysis, "canary", failed, false, nil, true, now)
+		Expect(status.Phase).To(Equal(meshmanagerv1.AnalysisRunning))
+		status = Next(status, svc.Analysis, "canary", failed, false, nil, true, now)
+		Expect(status.Phase).To(Equal(meshmanagerv1.AnalysisAborted))
+
+		// 새 커밋이 들어오면 처음부터 다시 분석
+		status = Next(status, svc.Analysis, "canary-2", passed, false, nil, true, now)
+		Expect(status.Phase).To(Equal(meshmanagerv1.AnalysisRunning))
+		Expect(status.ConsecutiveSuccesses).To(Equal(1))
+	})
+
+	It("does not promote while steps own the rollout", func() {
+		passed := []meshmanagerv1.AnalysisRuleResult{{Name: "errors", Value: "0", Max: "0.05", Passed: true}}
+		var status *meshmanagerv1.AnalysisStatus
+		for i := 0; i < 5; i++ {
+			status = Next(status, svc.Analysis, "canary", passed, false, nil, false, time.Now())
+		}
+		Expect(status.Phase).To(Equal(meshmanagerv1.AnalysisRunning))
+	})
+})
@@ -0,0 +1,95 @@
+package generators
+
+import (
+	"fmt"
+	"strings"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	apiv1beta1 "istio.io/api/networking/v1beta1"
+)
+
+// abTestDefaultCommit 규칙에 맞지 않는 요청의 커밋
+func abTestDefaultCommit(svc meshmanagerv1.ServiceConfig) string {
+	if svc.ABTest != nil && svc.ABTest.DefaultCommitHash != "" {
+		return svc.ABTest.DefaultCommitHash
+	}
+	if len(svc.CommitHashes) == 0 {
+		return ""
+	}
+	return svc.CommitHashes[0]
+}
+
+// generateABTestRoutes 규칙 순서대로 라우트를 만들고 마지막에 기본 커밋 라우트 추가.
+// uri 가 있으면(ingress) 모든 규칙에 함께 적용하고 rewrite 설정
+func generateABTestRoutes(svc meshmanagerv1.ServiceConfig, uri *apiv1beta1.StringMatch, rewrite *apiv1beta1.HTTPRewrite) []*apiv1beta1.HTTPRoute {
+	var routes []*apiv1beta1.HTTPRoute
+
+	if svc.ABTest != nil {
+		for _, rule := range svc.ABTest.Rules {
+			routes = append(routes, &apiv1beta1.HTTPRoute{
+				Match:   []*apiv1beta1.HTTPMatchRequest{abTestMatch(rule, uri)},
+				Rewrite: rewrite,
+				Route: []*apiv1beta1.HTTPRouteDestination{
+					{
+						Destination: &apiv1beta1.Destination{
+							Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
+							Subset: rule.CommitHash,
+						},
+					},
+				},
+			})
+		}
+	}
+
+	defaultRoute := &apiv1beta1.HTTPRoute{
+		Rewrite: rewrite,
+		Route: []*apiv1beta1.HTTPRouteDestination{
+			{
+				Destination: &apiv1beta1.Destination{
+					Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
+					Subset: abTestDefaultCommit(svc),
+				},
+			},
+		},
+	}
+	if uri != nil {
+		defaultRoute.Match = []*apiv1beta1.HTTPMatchRequest{{Uri: uri}}
+	}
+
+	return append(routes, defaultRoute)
+}
+
+// abTestMatch 규칙의 모든 조건을 하나의 HTTPMatchRequest 로 (AND)
+func abTestMatch(rule meshmanagerv1.ABTestRule, uri *apiv1beta1.StringMatch) *apiv1beta1.HTTPMatchRequest {
+	match := &apiv1beta1.HTTPMatchRequest{Uri: uri}
+
+	for _, h := range rule.Headers {
+		if match.Headers == nil {
+			match.Headers = make(map[string]*apiv1beta1.StringMatch)
+		}
+		match.Headers[strings.ToLower(h.Name)] = valueMatch(h)
+	}
+	if rule.UserAgent != "" {
+		if match.Headers == nil {
+			match.Headers = make(map[string]*apiv1beta1.StringMatch)
+		}
+		match.Headers["user-agent"] = &apiv1beta1.StringMatch{
+			MatchType: &apiv1beta1.StringMatch_Regex{Regex: rule.UserAgent},
+		}
+	}
+	for _, q := range rule.QueryParams {
+		if match.QueryParams == nil {
+			match.QueryParams = make(map[string]*apiv1beta1.StringMatch)
+		}
+		match.QueryParams[q.Name] = valueMatch(q)
+	}
+
+	return match
+}
+
+func valueMatch(v meshmanagerv1.ValueMatch) *apiv1beta1.StringMatch {
+	if v.Regex != "" {
+		return &apiv1beta1.StringMatch{MatchType: &apiv1beta1.StringMatch_Regex{Regex: v.Regex}}
+	}
+	return &apiv1beta1.StringMatch{MatchType: &apiv1beta1.StringMatch_Exact{Exact: v.Exact}}
+}
@@ -0,0 +1,107 @@
+package generators
+
+import (
+	"fmt"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	apiv1beta1 "istio.io/api/networking/v1beta1"
+)
+
+// blueGreenCommits active 커밋과 preview 커밋. 설정이 없으면 CommitHashes 순서대로
+func blueGreenCommits(svc meshmanagerv1.ServiceConfig) (active, preview string) {
+	if len(svc.CommitHashes) == 0 {
+		return "", ""
+	}
+
+	active = svc.CommitHashes[0]
+	if svc.BlueGreen != nil && svc.BlueGreen.ActiveCommitHash != "" {
+		active = svc.BlueGreen.ActiveCommitHash
+	}
+	for _, hash := range svc.CommitHashes {
+		if hash != active {
+			preview = hash
+			break
+		}
+	}
+	return active, preview
+}
+
+func blueGreenPreviewHeader(svc meshmanagerv1.ServiceConfig) string {
+	if svc.BlueGreen != nil && svc.BlueGreen.PreviewHeader != "" {
+		return svc.BlueGreen.PreviewHeader
+	}
+	return meshmanagerv1.DefaultPreviewHeader
+}
+
+// generateBlueGreenRoutes preview 헤더가 있는 요청만 preview 커밋으로, 나머지는 active 커밋으로
+func generateBlueGreenRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
+	var routes []*apiv1beta1.HTTPRoute
+
+	if _, preview := blueGreenCommits(svc); preview != "" {
+		routes = append(routes, &apiv1beta1.HTTPRoute{
+			Match: []*apiv1beta1.HTTPMatchRequest{
+				{
+					Headers: map[string]*apiv1beta1.StringMatch{
+						blueGreenPreviewHeader(svc): {
+							MatchType: &apiv1beta1.StringMatch_Exact{Exact: "true"},
+						},
+					},
+				},
+			},
+			Route: []*apiv1beta1.HTTPRouteDestination{
+				{
+					Destination: &apiv1beta1.Destination{
+						Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
+						Subset: preview,
+					},
+				},
+			},
+		})
+	}
+
+	return append(routes, generateStandardRoutes(svc)...)
+}
+
+// generateBlueGreenIngressRoutes 게이트웨이의 preview 경로/헤더 라우트. 일반 라우트보다 앞에 위치
+func generateBlueGreenIngressRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
+	_, preview := blueGreenCommits(svc)
+	if preview == "" {
+		return nil
+	}
+
+	destination := []*apiv1beta1.HTTPRouteDestination{{
+		Destination: &apiv1beta1.Destination{
+			Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
+			Subset: preview,
+		},
+	}}
+
+	var routes []*apiv1beta1.HTTPRoute
+
+	if svc.BlueGreen != nil && svc.BlueGreen.PreviewPathPrefix != "" {
+		// preview 경로도 서비스 경로와 같은 rewrite 대상으로
+		rewrite := *ingressSettings(svc).Rewrite
+		routes = append(routes, &apiv1beta1.HTTPRoute{
+			Match: []*apiv1beta1.HTTPMatchRequest{{
+				Uri: pathMatch(meshmanagerv1.IngressPathPrefix, svc.BlueGreen.PreviewPathPrefix),
+			}},
+			Rewrite: pathRewrite(meshmanagerv1.IngressPathPrefix, svc.BlueGreen.PreviewPathPrefix, rewrite),
+			Route:   destination,
+		})
+	}
+
+	routes = append(routes, &apiv1beta1.HTTPRoute{
+		Match: []*apiv1beta1.HTTPMatchRequest{{
+			Uri: ingressURIMatch(svc),
+			Headers: map[string]*apiv1beta1.StringMatch{
+				blueGreenPreviewHeader(svc): {
+					MatchType: &apiv1beta1.StringMatch_Exact{Exact: "true"},
+				},
+			},
+		}},
+		Rewrite: ingressRewrite(svc),
+		Route:   destination,
+	})
+
+	return routes
+}
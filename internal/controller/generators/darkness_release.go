@@ -0,0 +1,281 @@
+package generators
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	apiv1beta1 "istio.io/api/networking/v1beta1"
+)
+
+// DarknessReleaseHeader 게이트웨이 Lua 가 다크니스 릴리즈 대상 클라이언트에 붙이는 헤더. 값은 커밋 해시
+const DarknessReleaseHeader = "x-darkness-release"
+
+// 클라이언트 IP 를 바이트 배열로 변환하고 CIDR 포함 여부를 확인하는 함수
+const luaCIDRMatch = `
+local function parse_ip(ip)
+	ip = string.gsub(ip, "%%.*$", "")
+	local mapped = string.match(ip, "^::[fF][fF][fF][fF]:(%d+%.%d+%.%d+%.%d+)$")
+	if mapped then
+		ip = mapped
+	end
+
+	if not string.find(ip, ":", 1, true) then
+		local a, b, c, d = string.match(ip, "^(%d+)%.(%d+)%.(%d+)%.(%d+)$")
+		if not a then
+			return nil
+		end
+		return { tonumber(a), tonumber(b), tonumber(c), tonumber(d) }
+	end
+
+	local left, right = {}, {}
+	local gap = string.find(ip, "::", 1, true)
+	local head = gap and string.sub(ip, 1, gap - 1) or ip
+	for g in string.gmatch(head, "[^:]+") do
+		left[#left + 1] = tonumber(g, 16)
+	end
+	if gap then
+		for g in string.gmatch(string.sub(ip, gap + 2), "[^:]+") do
+			right[#right + 1] = tonumber(g, 16)
+		end
+	end
+
+	local groups = left
+	if gap then
+		for _ = 1, 8 - #left - #right do
+			groups[#groups + 1] = 0
+		end
+		for _, g in ipairs(right) do
+			groups[#groups + 1] = g
+		end
+	end
+	if #groups ~= 8 then
+		return nil
+	end
+
+	local bytes = {}
+	for _, g in ipairs(groups) do
+		if not g then
+			return nil
+		end
+		bytes[#bytes + 1] = math.floor(g / 256)
+		bytes[#bytes + 1] = g % 256
+	end
+	return bytes
+end
+
+local function in_cidr(bytes, network, bits)
+	if #bytes ~= #network then
+		return false
+	end
+	for i = 1, #network do
+		if bits <= 0 then
+			return true
+		end
+		if bits < 8 then
+			local unit = 2 ^ (8 - bits)
+			return math.floor(bytes[i] / unit) == math.floor(network[i] / unit)
+		end
+		if bytes[i] ~= network[i] then
+			return false
+		end
+		bits = bits - 8
+	end
+	return true
+end
+`
+
+// JWT payload 에서 claim 값을 꺼내는 함수. b64decode 필요
+const luaJWTClaim = `
+local function jwt_claim(token, claim)
+	if not token then
+		return nil
+	end
+	token = string.gsub(token, "^[Bb]earer%s+", "")
+	local payload = string.match(token, "^[^.]+%.([^.]+)")
+	if not payload then
+		return nil
+	end
+	local claims = b64decode(payload)
+	return string.match(claims, '"' .. claim .. '"%s*:%s*"([^"]*)"') or string.match(claims, '"' .. claim .. '"%s*:%s*([%d%.%-]+)')
+end
+`
+
+// darknessNeedsLua IP/CIDR 나 사용자 ID 조건은 VS 만으로 매칭할 수 없어 게이트웨이 Lua 로 태깅
+func darknessNeedsLua(svc meshmanagerv1.ServiceConfig) bool {
+	for _, dr := range svc.DarknessReleases {
+		if len(dr.IPs) > 0 || len(dr.UserIDs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// darknessMatches 다크니스 릴리즈 조건별 HTTPMatchRequest. 각 항목은 OR 로 동작하며
+// uri 가 있으면(ingress) 모든 항목에 함께 적용
+func darknessMatches(dr meshmanagerv1.DarknessRelease, uri *apiv1beta1.StringMatch) []*apiv1beta1.HTTPMatchRequest {
+	var matches []*apiv1beta1.HTTPMatchRequest
+
+	if len(dr.IPs) > 0 || len(dr.UserIDs) > 0 {
+		matches = append(matches, &apiv1beta1.HTTPMatchRequest{
+			Uri: uri,
+			Headers: map[string]*apiv1beta1.StringMatch{
+				DarknessReleaseHeader: {
+					MatchType: &apiv1beta1.StringMatch_Exact{Exact: dr.CommitHash},
+				},
+			},
+		})
+	}
+
+	for _, h := range dr.Headers {
+		matches = append(matches, &apiv1beta1.HTTPMatchRequest{
+			Uri: uri,
+			Headers: map[string]*apiv1beta1.StringMatch{
+				h.Name: {
+					MatchType: &apiv1beta1.StringMatch_Exact{Exact: h.Value},
+				},
+			},
+		})
+	}
+
+	for _, c := range dr.Cookies {
+		matches = append(matches, &apiv1beta1.HTTPMatchRequest{
+			Uri: uri,
+			Headers: map[string]*apiv1beta1.StringMatch{
+				"cookie": {
+					MatchType: &apiv1beta1.StringMatch_Regex{
+						Regex: fmt.Sprintf(`^(.*;\s*)?%s=%s(;.*)?$`, regexp.QuoteMeta(c.Name), regexp.QuoteMeta(c.Value)),
+					},
+				},
+			},
+		})
+	}
+
+	return matches
+}
+
+// luaQuote Lua 문자열 리터럴로 변환
+func luaQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// parseDarknessIP 단일 IP(v4/v6) 또는 CIDR 를 네트워크로 변환. 단일 IP 는 /32, /128 로 취급
+func parseDarknessIP(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		return network, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", value)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// buildDarknessReleaseLuaScript 클라이언트 IP 가 IPs 의 IP/CIDR 에 속하거나 JWT claim 이 UserIDs 에 있으면
+// x-darkness-release 헤더에 커밋 해시를 붙이는 스크립트. 외부에서 보낸 같은 헤더는 제거
+func buildDarknessReleaseLuaScript(svc meshmanagerv1.ServiceConfig) string {
+	var networks, users []string
+	for _, dr := range svc.DarknessReleases {
+		for _, ip := range dr.IPs {
+			network, err := parseDarknessIP(ip)
+			if err != nil {
+				// 웹훅에서 거르지 못한 값은 무시
+				continue
+			}
+
+			bytes := network.IP
+			bits, _ := network.Mask.Size()
+			if v4 := bytes.To4(); v4 != nil {
+				// ::ffff:a.b.c.d/104 처럼 IPv4-mapped 로 쓴 CIDR 은 Lua 가 IPv4 로 비교하므로 prefix 도 맞춰줌
+				if len(network.Mask) == net.IPv6len {
+					bits = max(bits-96, 0)
+				}
+				bytes = v4
+			}
+			octets := make([]string, len(bytes))
+			for i, b := range bytes {
+				octets[i] = fmt.Sprint(b)
+			}
+
+			networks = append(networks, fmt.Sprintf(`	{ network = { %s }, bits = %d, commit = "%s" },`,
+				strings.Join(octets, ", "), bits, dr.CommitHash))
+		}
+
+		if len(dr.UserIDs) > 0 {
+			ids := make([]string, len(dr.UserIDs))
+			for i, id := range dr.UserIDs {
+				ids[i] = fmt.Sprintf("[%s] = true", luaQuote(id))
+			}
+			users = append(users, fmt.Sprintf(`	{ header = %s, claim = %s, commit = "%s", ids = { %s } },`,
+				luaQuote(dr.TokenHeader), luaQuote(luaPatternEscape(dr.UserClaim)), dr.CommitHash, strings.Join(ids, ", ")))
+		}
+	}
+
+	helpers := luaCIDRMatch
+	if len(users) > 0 {
+		helpers += luaBase64Decode + luaJWTClaim
+	}
+
+	return fmt.Sprintf(`%s
+local darkness_networks = {
+%s
+}
+
+local darkness_users = {
+%s
+}
+
+function envoy_on_request(request_handle)
+	local headers = request_handle:headers()
+  	local path = headers:get(":path")
+  	local host = string.gsub(string.lower(headers:get(":authority") or ""), ":%%d+$", "")
+
+  	if %s then
+		headers:remove("%s")
+
+		local ip = headers:get("x-envoy-external-address")
+		if not ip then
+			local remote = request_handle:streamInfo():downstreamRemoteAddress()
+			if remote then
+				ip = string.match(remote, "^%%[(.+)%%]:%%d+$") or string.match(remote, "^([^:]+):%%d+$") or remote
+			end
+		end
+
+		local commit = nil
+		local bytes = ip and parse_ip(ip)
+		if bytes then
+			for _, release in ipairs(darkness_networks) do
+				if in_cidr(bytes, release.network, release.bits) then
+					commit = release.commit
+					break
+				end
+			end
+		end
+
+		if not commit then
+			for _, release in ipairs(darkness_users) do
+				local id = jwt_claim(headers:get(release.header), release.claim)
+				if id and release.ids[id] then
+					commit = release.commit
+					break
+				end
+			end
+		end
+
+		if commit then
+			headers:add("%s", commit)
+		end
+	end
+end`, helpers, strings.Join(networks, "\n"), strings.Join(users, "\n"), ingressMatchLua(svc), DarknessReleaseHeader, DarknessReleaseHeader)
+}
@@ -4,13 +4,14 @@ import (
 	"fmt"
 	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	apiv1beta1 "istio.io/api/networking/v1beta1"
 	istiov1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"time"
 )
 
-func GenerateDestinationRule(svc meshmanagerv1.ServiceConfig) *istiov1beta1.DestinationRule {
+func GenerateDestinationRule(svc meshmanagerv1.ServiceConfig) (*istiov1beta1.DestinationRule, error) {
 	dr := &istiov1beta1.DestinationRule{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      svc.Name,
@@ -54,17 +55,22 @@ func GenerateDestinationRule(svc meshmanagerv1.ServiceConfig) *istiov1beta1.Dest
 		dr.Spec.TrafficPolicy = &apiv1beta1.TrafficPolicy{
 			LoadBalancer: &apiv1beta1.LoadBalancerSettings{
 				LbPolicy: &apiv1beta1.LoadBalancerSettings_ConsistentHash{
-					ConsistentHash: &apiv1beta1.LoadBalancerSettings_ConsistentHashLB{
-						HashKey: &apiv1beta1.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{
-							HttpHeaderName: "x-session-id",
-						},
-					},
+					ConsistentHash: stickyHashKey(svc),
 				},
 			},
 		}
+	}
 
-		if svc.OutlierDetection != nil {
-			addOutlierDetection(dr, svc.OutlierDetection)
+	// 이상 감지는 모든 타입에 적용
+	if svc.OutlierDetection != nil {
+		if err := addOutlierDetection(dr, svc.OutlierDetection); err != nil {
+			return nil, err
+		}
+	}
+
+	if svc.TrafficPolicy != nil {
+		if err := addTrafficPolicy(dr, svc.TrafficPolicy); err != nil {
+			return nil, err
 		}
 	}
 
@@ -73,21 +79,49 @@ func GenerateDestinationRule(svc meshmanagerv1.ServiceConfig) *istiov1beta1.Dest
 		dr.Spec.TrafficPolicy = &apiv1beta1.TrafficPolicy{}
 	}
 
-	return dr
+	return dr, nil
 }
 
-func addOutlierDetection(dr *istiov1beta1.DestinationRule, config *meshmanagerv1.OutlierDetection) {
-	intervalDuration, _ := time.ParseDuration(config.Interval)
+func addOutlierDetection(dr *istiov1beta1.DestinationRule, config *meshmanagerv1.OutlierDetection) error {
+	outlier := &apiv1beta1.OutlierDetection{
+		SplitExternalLocalOriginErrors: config.SplitExternalLocalOriginErrors,
+	}
 
-	if dr.Spec.TrafficPolicy == nil {
-		dr.Spec.TrafficPolicy = &apiv1beta1.TrafficPolicy{}
+	// 명시한 0 은 그대로 보내야 Istio 기본값(5)이 적용되지 않음
+	if config.Consecutive5xxErrors != nil {
+		outlier.Consecutive_5XxErrors = wrapperspb.UInt32(uint32(*config.Consecutive5xxErrors))
+	}
+	if config.ConsecutiveGatewayErrors > 0 {
+		outlier.ConsecutiveGatewayErrors = wrapperspb.UInt32(uint32(config.ConsecutiveGatewayErrors))
+	}
+	if config.ConsecutiveLocalOriginFailures > 0 {
+		outlier.ConsecutiveLocalOriginFailures = wrapperspb.UInt32(uint32(config.ConsecutiveLocalOriginFailures))
 	}
 
-	dr.Spec.TrafficPolicy.OutlierDetection = &apiv1beta1.OutlierDetection{
-		ConsecutiveErrors:  int32(config.Consecutive5xxErrors),
-		Interval:           durationpb.New(intervalDuration),
-		BaseEjectionTime:   durationpb.New(30 * time.Second),
-		MaxEjectionPercent: 100,
-		MinHealthPercent:   50,
+	if config.Interval != "" {
+		interval, err := time.ParseDuration(config.Interval)
+		if err != nil {
+			return fmt.Errorf("outlierDetection.interval %q: %w", config.Interval, err)
+		}
+		outlier.Interval = durationpb.New(interval)
+	}
+	if config.BaseEjectionTime != "" {
+		baseEjectionTime, err := time.ParseDuration(config.BaseEjectionTime)
+		if err != nil {
+			return fmt.Errorf("outlierDetection.baseEjectionTime %q: %w", config.BaseEjectionTime, err)
+		}
+		outlier.BaseEjectionTime = durationpb.New(baseEjectionTime)
+	}
+	if config.MaxEjectionPercent != nil {
+		outlier.MaxEjectionPercent = *config.MaxEjectionPercent
+	}
+	if config.MinHealthPercent != nil {
+		outlier.MinHealthPercent = *config.MinHealthPercent
+	}
+
+	if dr.Spec.TrafficPolicy == nil {
+		dr.Spec.TrafficPolicy = &apiv1beta1.TrafficPolicy{}
 	}
+	dr.Spec.TrafficPolicy.OutlierDetection = outlier
+	return nil
 }
@@ -10,10 +10,42 @@ import (
 	"strings"
 )
 
+// NeedsEnvoyFilter 게이트웨이 Lua 가 필요한 서비스인지 여부.
+// BlueGreenType, ABTestType, Weighted 모드 CanaryType 은 다크니스 릴리즈 태깅이 필요할 때만 생성
+func NeedsEnvoyFilter(svc meshmanagerv1.ServiceConfig) bool {
+	return hasVersionLua(svc) || darknessNeedsLua(svc)
+}
+
+func envoyFilterName(svc meshmanagerv1.ServiceConfig) string {
+	return fmt.Sprintf("%s-filter", svc.Name)
+}
+
+// hasVersionLua x-canary-version 헤더를 설정하는 스크립트가 필요한 타입
+func hasVersionLua(svc meshmanagerv1.ServiceConfig) bool {
+	switch svc.Type {
+	case meshmanagerv1.CanaryType:
+		return !weightedRouting(svc)
+	case meshmanagerv1.StandardType, meshmanagerv1.StickyCanaryType, meshmanagerv1.MirrorType:
+		return true
+	default:
+		return false
+	}
+}
+
 func GenerateEnvoyFilter(svc meshmanagerv1.ServiceConfig, istioRoute *meshmanagerv1.IstioRoute) *istiov1beta1.EnvoyFilter {
+	var patches []*apiv1beta1.EnvoyFilter_EnvoyConfigObjectPatch
+	if hasVersionLua(svc) {
+		patches = append(patches, luaFilterPatch(buildLuaFilterConfig(svc)))
+	}
+
+	// 다크니스 릴리즈 대상 클라이언트 태깅용 Lua 필터 추가
+	if darknessNeedsLua(svc) {
+		patches = append(patches, luaFilterPatch(luaFilterConfig(buildDarknessReleaseLuaScript(svc))))
+	}
+
 	return &istiov1beta1.EnvoyFilter{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-filter", svc.Name),
+			Name:      envoyFilterName(svc),
 			Namespace: "istio-system",
 			Labels: map[string]string{
 				"managed-by":           "istioroute-controller",
@@ -30,30 +62,33 @@ func GenerateEnvoyFilter(svc meshmanagerv1.ServiceConfig, istioRoute *meshmanage
 		},
 		Spec: apiv1beta1.EnvoyFilter{
 			WorkloadSelector: &apiv1beta1.WorkloadSelector{
-				Labels: map[string]string{"istio": "ingressgateway"},
+				Labels: gatewaySettings(istioRoute).Selector,
 			},
-			ConfigPatches: []*apiv1beta1.EnvoyFilter_EnvoyConfigObjectPatch{
-				{
-					ApplyTo: apiv1beta1.EnvoyFilter_HTTP_FILTER,
-					Match: &apiv1beta1.EnvoyFilter_EnvoyConfigObjectMatch{
-						Context: apiv1beta1.EnvoyFilter_GATEWAY,
-						ObjectTypes: &apiv1beta1.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
-							Listener: &apiv1beta1.EnvoyFilter_ListenerMatch{
-								FilterChain: &apiv1beta1.EnvoyFilter_ListenerMatch_FilterChainMatch{
-									Filter: &apiv1beta1.EnvoyFilter_ListenerMatch_FilterMatch{
-										Name: "envoy.filters.network.http_connection_manager",
-									},
-								},
-							},
+			ConfigPatches: patches,
+		},
+	}
+}
+
+// luaFilterPatch 게이트웨이 HTTP 필터 체인 앞에 Lua 필터를 삽입하는 패치
+func luaFilterPatch(value *structpb.Struct) *apiv1beta1.EnvoyFilter_EnvoyConfigObjectPatch {
+	return &apiv1beta1.EnvoyFilter_EnvoyConfigObjectPatch{
+		ApplyTo: apiv1beta1.EnvoyFilter_HTTP_FILTER,
+		Match: &apiv1beta1.EnvoyFilter_EnvoyConfigObjectMatch{
+			Context: apiv1beta1.EnvoyFilter_GATEWAY,
+			ObjectTypes: &apiv1beta1.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
+				Listener: &apiv1beta1.EnvoyFilter_ListenerMatch{
+					FilterChain: &apiv1beta1.EnvoyFilter_ListenerMatch_FilterChainMatch{
+						Filter: &apiv1beta1.EnvoyFilter_ListenerMatch_FilterMatch{
+							Name: "envoy.filters.network.http_connection_manager",
 						},
 					},
-					Patch: &apiv1beta1.EnvoyFilter_Patch{
-						Operation: apiv1beta1.EnvoyFilter_Patch_INSERT_BEFORE,
-						Value:     buildLuaFilterConfig(svc),
-					},
 				},
 			},
 		},
+		Patch: &apiv1beta1.EnvoyFilter_Patch{
+			Operation: apiv1beta1.EnvoyFilter_Patch_INSERT_BEFORE,
+			Value:     value,
+		},
 	}
 }
 
@@ -72,10 +107,14 @@ func buildLuaFilterConfig(svc meshmanagerv1.ServiceConfig) *structpb.Struct {
 		luaScript = buildCanaryLuaScript(svc)
 	case baseType == meshmanagerv1.StickyCanaryType:
 		luaScript = buildStickyCanaryLuaScript(svc)
-	case baseType == meshmanagerv1.StandardType:
+	case baseType == meshmanagerv1.StandardType, baseType == meshmanagerv1.MirrorType:
 		luaScript = buildStandardLuaScript(svc)
 	}
 
+	return luaFilterConfig(luaScript)
+}
+
+func luaFilterConfig(luaScript string) *structpb.Struct {
 	return &structpb.Struct{
 		Fields: map[string]*structpb.Value{
 			"name": structpb.NewStringValue("envoy.lua"),
@@ -95,11 +134,12 @@ func buildStandardLuaScript(svc meshmanagerv1.ServiceConfig) string {
 function envoy_on_request(request_handle)
 	local headers = request_handle:headers()
   	local path = headers:get(":path")
+  	local host = string.gsub(string.lower(headers:get(":authority") or ""), ":%%d+$", "")
   
-  	if string.find(path, "^/%s") then
+  	if %s then
 		headers:add("x-canary-version","%s")
 	end
-end`, svc.Name, svc.CommitHashes[0])
+end`, ingressMatchLua(svc), svc.CommitHashes[0])
 }
 
 func buildCanaryLuaScript(svc meshmanagerv1.ServiceConfig) string {
@@ -107,18 +147,17 @@ func buildCanaryLuaScript(svc meshmanagerv1.ServiceConfig) string {
 		return ""
 	}
 
-	ratioValue := *svc.Ratio
-
-	return fmt.Sprintf(`
+	return fmt.Sprintf(`%s
 function envoy_on_request(request_handle)
 	local headers = request_handle:headers()
   	local path = headers:get(":path")
+  	local host = string.gsub(string.lower(headers:get(":authority") or ""), ":%%d+$", "")
   
-  	if string.find(path, "^/%s") then
+  	if %s then
 		local rand = math.random(0, 99)
-		headers:add("x-canary-version", rand > %d and "%s" or "%s")
+		headers:add("x-canary-version", %s)
 	end
-end`, svc.Name, ratioValue, svc.CommitHashes[0], svc.CommitHashes[1])
+end`, versionLuaHelpers(svc), ingressMatchLua(svc), versionPickExpr(svc, "rand"))
 }
 
 func buildStickyCanaryLuaScript(svc meshmanagerv1.ServiceConfig) string {
@@ -126,26 +165,17 @@ func buildStickyCanaryLuaScript(svc meshmanagerv1.ServiceConfig) string {
 		return ""
 	}
 
-	ratioValue := *svc.Ratio
-
-	return fmt.Sprintf(`
+	return fmt.Sprintf(`%s
 function envoy_on_request(request_handle)
 	local headers = request_handle:headers()
   	local path = headers:get(":path")
+  	local host = string.gsub(string.lower(headers:get(":authority") or ""), ":%%d+$", "")
   
-  	if string.find(path, "^/%s") then
-		local jwt = headers:get("jwt")
-		if jwt then
-			local hash = 0
-			for i = 1, #jwt do
-				hash = (hash * 31 + jwt:byte(i)) %% 100
-			end
-			
-			headers:add("x-canary-version", hash > %d and "%s" or "%s")
-			headers:add("x-session-id", tostring(math.floor(hash)))
-		end
+  	if %s then
+%s
+%s
 	end
-end`, svc.Name, ratioValue, svc.CommitHashes[0], svc.CommitHashes[1])
+end%s`, stickyLuaHelpers(svc), ingressMatchLua(svc), stickyKeyLua(svc), stickyVersionLua(svc), stickyResponseLua(svc))
 }
 
 func buildCanaryDependentLuaScript(svc meshmanagerv1.ServiceConfig) string {
@@ -153,8 +183,6 @@ func buildCanaryDependentLuaScript(svc meshmanagerv1.ServiceConfig) string {
 		return ""
 	}
 
-	ratioValue := *svc.Ratio
-
 	// Build dependency header additions
 	var depHeaderLines []string
 	for _, dep := range svc.Dependencies {
@@ -165,12 +193,13 @@ func buildCanaryDependentLuaScript(svc meshmanagerv1.ServiceConfig) string {
 	}
 	depHeaderCode := strings.Join(depHeaderLines, "\n")
 
-	return fmt.Sprintf(`
+	return fmt.Sprintf(`%s
 function envoy_on_request(request_handle)
 	local headers = request_handle:headers()
   	local path = headers:get(":path")
+  	local host = string.gsub(string.lower(headers:get(":authority") or ""), ":%%d+$", "")
   
-  	if string.find(path, "^/%s") then
+  	if %s then
 		local jwt = headers:get("jwt")
 		if jwt then
 			local hash = 0
@@ -178,11 +207,11 @@ function envoy_on_request(request_handle)
 				hash = (hash * 31 + jwt:byte(i)) %% 100
 			end
 			
-			headers:add("x-canary-version", hash > %d and "%s" or "%s")
+			headers:add("x-canary-version", %s)
 %s
 		end
 	end
-end`, svc.Name, ratioValue, svc.CommitHashes[0], svc.CommitHashes[1], depHeaderCode)
+end`, versionLuaHelpers(svc), ingressMatchLua(svc), versionPickExpr(svc, "hash"), depHeaderCode)
 }
 
 func buildStickyCanaryDependentLuaScript(svc meshmanagerv1.ServiceConfig) string {
@@ -190,35 +219,26 @@ func buildStickyCanaryDependentLuaScript(svc meshmanagerv1.ServiceConfig) string
 		return ""
 	}
 
-	ratioValue := *svc.Ratio
-
 	// Build dependency header additions
 	var depHeaderLines []string
 	for _, dep := range svc.Dependencies {
 		if len(dep.CommitHashes) > 0 {
-			line := fmt.Sprintf(`			headers:add("x-%s-version", "%s")`, dep.Name, dep.CommitHashes[0])
+			line := fmt.Sprintf(`		headers:add("x-%s-version", "%s")`, dep.Name, dep.CommitHashes[0])
 			depHeaderLines = append(depHeaderLines, line)
 		}
 	}
 	depHeaderCode := strings.Join(depHeaderLines, "\n")
 
-	return fmt.Sprintf(`
+	return fmt.Sprintf(`%s
 function envoy_on_request(request_handle)
 	local headers = request_handle:headers()
   	local path = headers:get(":path")
+  	local host = string.gsub(string.lower(headers:get(":authority") or ""), ":%%d+$", "")
   
-  	if string.find(path, "^/%s") then
-		local jwt = headers:get("jwt")
-		if jwt then
-			local hash = 0
-			for i = 1, #jwt do
-				hash = (hash * 31 + jwt:byte(i)) %% 100
-			end
-			
-			headers:add("x-canary-version", hash > %d and "%s" or "%s")
-			headers:add("x-session-id", tostring(math.floor(hash)))
+  	if %s then
+%s
+%s
 %s
-		end
 	end
-end`, svc.Name, ratioValue, svc.CommitHashes[0], svc.CommitHashes[1], depHeaderCode)
+end%s`, stickyLuaHelpers(svc), ingressMatchLua(svc), stickyKeyLua(svc), stickyVersionLua(svc), depHeaderCode, stickyResponseLua(svc))
 }
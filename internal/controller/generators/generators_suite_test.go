@@ -0,0 +1,14 @@
+package generators
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGenerators(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Generators Suite")
+}
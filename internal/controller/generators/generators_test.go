@@ -0,0 +1,428 @@
+package generators
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	istiov1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+)
+
+func intPtr(v int) *int { return &v }
+
+func int32Ptr(v int32) *int32 { return &v }
+
+func strPtr(v string) *string { return &v }
+
+// newService 기본 ingress(/user -> /api, 모든 호스트)를 쓰는 두 커밋 서비스
+func newService(serviceType meshmanagerv1.ServiceType) meshmanagerv1.ServiceConfig {
+	return meshmanagerv1.ServiceConfig{
+		Name:         "user",
+		Namespace:    "shop",
+		Type:         serviceType,
+		CommitHashes: []string{"stable", "canary"},
+		Ratio:        intPtr(20),
+	}
+}
+
+var testRoute = &meshmanagerv1.IstioRoute{
+	ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop", UID: "uid"},
+}
+
+// luaScripts EnvoyFilter 패치 순서대로 inline_code 를 꺼냄
+func luaScripts(ef *istiov1alpha3.EnvoyFilter) []string {
+	var scripts []string
+	for _, patch := range ef.Spec.ConfigPatches {
+		typed := patch.Patch.Value.Fields["typed_config"].GetStructValue()
+		scripts = append(scripts, typed.Fields["inline_code"].GetStringValue())
+	}
+	return scripts
+}
+
+// envoyRewrite Envoy regex rewrite 의 \1 을 Go 치환 문법으로 바꿔 적용
+func envoyRewrite(match, rewrite, path string) string {
+	return regexp.MustCompile(match).ReplaceAllString(path, strings.ReplaceAll(rewrite, `\1`, "${1}"))
+}
+
+var _ = Describe("Darkness release", func() {
+	darknessService := func(release meshmanagerv1.DarknessRelease) meshmanagerv1.ServiceConfig {
+		svc := newService(meshmanagerv1.StandardType)
+		svc.CommitHashes = []string{"stable"}
+		svc.DarknessReleases = []meshmanagerv1.DarknessRelease{release}
+		return svc
+	}
+
+	DescribeTable("writes the network Lua compares client addresses against",
+		func(ip, entry string) {
+			svc := darknessService(meshmanagerv1.DarknessRelease{CommitHash: "canary", IPs: []string{ip}})
+
+			scripts := luaScripts(GenerateEnvoyFilter(svc, testRoute))
+			Expect(scripts).To(HaveLen(2))
+			Expect(scripts[1]).To(ContainSubstring(entry))
+		},
+		Entry("IPv4 /0 covers every IPv4 client", "0.0.0.0/0",
+			`{ network = { 0, 0, 0, 0 }, bits = 0, commit = "canary" }`),
+		Entry("/17 clears the host bits of the third octet", "10.20.200.1/17",
+			`{ network = { 10, 20, 128, 0 }, bits = 17, commit = "canary" }`),
+		Entry("single IPv4 is /32", "10.20.30.40",
+			`{ network = { 10, 20, 30, 40 }, bits = 32, commit = "canary" }`),
+		Entry("IPv6 ::/0 covers every IPv6 client", "::/0",
+			`{ network = { 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0 }, bits = 0, commit = "canary" }`),
+		Entry("single IPv6 is /128", "2001:db8::1",
+			`{ network = { 32, 1, 13, 184, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1 }, bits = 128, commit = "canary" }`),
+		Entry("IPv4-mapped address compares as IPv4", "::ffff:192.168.0.1",
+			`{ network = { 192, 168, 0, 1 }, bits = 32, commit = "canary" }`),
+		Entry("IPv4-mapped CIDR drops the mapped 96-bit prefix", "::ffff:10.0.0.0/104",
+			`{ network = { 10, 0, 0, 0 }, bits = 8, commit = "canary" }`),
+	)
+
+	It("routes the tagged header to the darkness subset ahead of the main routes", func() {
+		svc := darknessService(meshmanagerv1.DarknessRelease{CommitHash: "canary", IPs: []string{"10.0.0.0/8"}})
+
+		vs, err := GenerateVirtualService(svc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vs.Spec.Http).To(HaveLen(2))
+		Expect(vs.Spec.Http[0].Match[0].Headers[DarknessReleaseHeader].GetExact()).To(Equal("canary"))
+		Expect(vs.Spec.Http[0].Route[0].Destination.Subset).To(Equal("canary"))
+		Expect(vs.Spec.Http[1].Route[0].Destination.Subset).To(Equal("stable"))
+
+		dr, err := GenerateDestinationRule(svc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dr.Spec.Subsets).To(HaveLen(2))
+	})
+
+	DescribeTable("includes the base64 decoder only for user ID matching",
+		func(release meshmanagerv1.DarknessRelease, decoder bool) {
+			release.CommitHash = "canary"
+			scripts := luaScripts(GenerateEnvoyFilter(darknessService(release), testRoute))
+
+			Expect(scripts).To(HaveLen(2))
+			if decoder {
+				Expect(scripts[1]).To(ContainSubstring("local function b64decode(data)"))
+				Expect(scripts[1]).To(ContainSubstring(`ids = { ["alice"] = true }`))
+			} else {
+				Expect(scripts[1]).NotTo(ContainSubstring("b64decode"))
+			}
+		},
+		Entry("IPs only", meshmanagerv1.DarknessRelease{IPs: []string{"10.0.0.1"}}, false),
+		Entry("user IDs", meshmanagerv1.DarknessRelease{UserIDs: []string{"alice"}, UserClaim: "sub", TokenHeader: "authorization"}, true),
+	)
+})
+
+var _ = Describe("Sticky session", func() {
+	DescribeTable("reads the session key from the configured source in Lua and in the hash policy",
+		func(session *meshmanagerv1.StickySession, header, cookie string, sourceIP bool, luaKey string) {
+			svc := newService(meshmanagerv1.StickyCanaryType)
+			svc.StickySession = session
+
+			dr, err := GenerateDestinationRule(svc)
+			Expect(err).NotTo(HaveOccurred())
+			hash := dr.Spec.TrafficPolicy.LoadBalancer.GetConsistentHash()
+			Expect(hash.GetHttpHeaderName()).To(Equal(header))
+			Expect(hash.GetHttpCookie().GetName()).To(Equal(cookie))
+			Expect(hash.GetUseSourceIp()).To(Equal(sourceIP))
+
+			script := luaScripts(GenerateEnvoyFilter(svc, testRoute))[0]
+			Expect(script).To(ContainSubstring(luaKey))
+		},
+		Entry("jwt header by default", nil, "jwt", "", false,
+			`local key = headers:get("jwt")`),
+		Entry("custom header",
+			&meshmanagerv1.StickySession{Source: meshmanagerv1.StickyHeaderSource, Name: "x-user"}, "x-user", "", false,
+			`local key = headers:get("x-user")`),
+		Entry("cookie",
+			&meshmanagerv1.StickySession{Source: meshmanagerv1.StickyCookieSource, Name: "sid"}, "", "sid", false,
+			`if k == "sid" then`),
+		Entry("client IP",
+			&meshmanagerv1.StickySession{Source: meshmanagerv1.StickyClientIPSource}, "", "", true,
+			`local key = headers:get("x-envoy-external-address")`),
+		Entry("JWT claim hashed by the Lua session id",
+			&meshmanagerv1.StickySession{Source: meshmanagerv1.StickyJWTClaimSource, Name: "sub"}, "x-session-id", "", false,
+			`local claims = b64decode(payload)`),
+	)
+
+	DescribeTable("pins the version with a session cookie only when a session duration is set",
+		func(sessionDuration int, header, cookie string) {
+			svc := newService(meshmanagerv1.StickyCanaryType)
+			svc.SessionDuration = sessionDuration
+
+			dr, err := GenerateDestinationRule(svc)
+			Expect(err).NotTo(HaveOccurred())
+			hash := dr.Spec.TrafficPolicy.LoadBalancer.GetConsistentHash()
+			Expect(hash.GetHttpHeaderName()).To(Equal(header))
+
+			script := luaScripts(GenerateEnvoyFilter(svc, testRoute))[0]
+			if cookie == "" {
+				Expect(hash.GetHttpCookie()).To(BeNil())
+				Expect(script).NotTo(ContainSubstring("set-cookie"))
+				return
+			}
+			Expect(hash.GetHttpCookie().GetName()).To(Equal(cookie))
+			Expect(hash.GetHttpCookie().GetTtl().AsDuration()).To(Equal(time.Duration(sessionDuration) * time.Second))
+			// Lua 가 커밋을 기록하는 쿠키와 Envoy 해시 쿠키는 서로 달라야 함
+			Expect(script).To(ContainSubstring(`"user-canary-version=" .. meta["user"] .. "; Max-Age=600`))
+			Expect(script).NotTo(ContainSubstring(cookie))
+		},
+		Entry("no session duration", 0, "jwt", ""),
+		Entry("session duration", 600, "", "user-canary-affinity"),
+	)
+})
+
+var _ = Describe("Ingress path", func() {
+	// Lua 조건식에서 string.find 패턴을 꺼냄. 이스케이프가 없는 경로라면 Go 정규식과 의미가 같음
+	luaPathPattern := regexp.MustCompile(`string\.find\(path, "([^"]+)"\)`)
+
+	DescribeTable("matches whole path segments in both the VirtualService and the Lua filter",
+		func(path string, matched bool) {
+			svc := newService(meshmanagerv1.CanaryType)
+
+			vs, err := GenerateIngressVirtualService(svc, "istio-system/gateway")
+			Expect(err).NotTo(HaveOccurred())
+			for _, route := range vs.Spec.Http {
+				uri := route.Match[0].Uri.GetRegex()
+				Expect(uri).To(Equal(`^/user(/.*)?$`))
+				Expect(regexp.MustCompile(uri).MatchString(path)).To(Equal(matched))
+			}
+
+			script := luaScripts(GenerateEnvoyFilter(svc, testRoute))[0]
+			Expect(script).To(ContainSubstring(`if path == "/user" or string.find(path, "^/user[/?]") then`))
+			pattern := luaPathPattern.FindStringSubmatch(script)[1]
+			Expect(path == "/user" || regexp.MustCompile(pattern).MatchString(path)).To(Equal(matched))
+		},
+		Entry("exact prefix", "/user", true),
+		Entry("trailing slash", "/user/", true),
+		Entry("nested path", "/user/items/1", true),
+		Entry("longer segment", "/users", false),
+		Entry("longer segment with path", "/username/1", false),
+		Entry("prefix elsewhere", "/api/user", false),
+	)
+
+	DescribeTable("rewrites only the matched prefix",
+		func(rewrite *string, path, expected string) {
+			svc := newService(meshmanagerv1.StandardType)
+			svc.Ingress = &meshmanagerv1.IngressConfig{Rewrite: rewrite}
+
+			vs, err := GenerateIngressVirtualService(svc, "istio-system/gateway")
+			Expect(err).NotTo(HaveOccurred())
+			rw := vs.Spec.Http[len(vs.Spec.Http)-1].Rewrite
+			if expected == path {
+				Expect(rw).To(BeNil())
+				return
+			}
+			Expect(rw.GetUri()).To(BeEmpty())
+			Expect(envoyRewrite(rw.UriRegexRewrite.Match, rw.UriRegexRewrite.Rewrite, path)).To(Equal(expected))
+		},
+		Entry("default /api", nil, "/user/items", "/api/items"),
+		Entry("default /api on the prefix itself", nil, "/user", "/api"),
+		Entry("root target", strPtr("/"), "/user/items", "/items"),
+		Entry("root target on the prefix itself", strPtr("/"), "/user", "/"),
+		Entry("disabled", strPtr(""), "/user/items", "/user/items"),
+	)
+
+	DescribeTable("limits the Lua filter to the ingress hosts",
+		func(hosts []string, condition string) {
+			svc := newService(meshmanagerv1.StandardType)
+			svc.Ingress = &meshmanagerv1.IngressConfig{Hosts: hosts}
+
+			script := luaScripts(GenerateEnvoyFilter(svc, testRoute))[0]
+			Expect(script).To(ContainSubstring(`local host = string.gsub(string.lower(headers:get(":authority") or ""), ":%d+$", "")`))
+			Expect(script).To(ContainSubstring("if " + condition + " then"))
+		},
+		Entry("all hosts", nil,
+			`path == "/user" or string.find(path, "^/user[/?]")`),
+		Entry("wildcard among hosts", []string{"api.example.com", "*"},
+			`path == "/user" or string.find(path, "^/user[/?]")`),
+		Entry("exact host is lowercased", []string{"API.example.com"},
+			`(host == "api.example.com") and (path == "/user" or string.find(path, "^/user[/?]"))`),
+		Entry("subdomain wildcard", []string{"api.example.com", "*.example.org"},
+			`(host == "api.example.com" or string.find(host, "^.+%.example%.org$")) and (path == "/user" or string.find(path, "^/user[/?]"))`),
+	)
+})
+
+var _ = Describe("Weights", func() {
+	DescribeTable("sends exactly ratio percent of header routed requests to the canary",
+		func(ratio int) {
+			svc := newService(meshmanagerv1.CanaryType)
+			svc.Ratio = intPtr(ratio)
+
+			script := luaScripts(GenerateEnvoyFilter(svc, testRoute))[0]
+			Expect(script).To(ContainSubstring("local rand = math.random(0, 99)"))
+			Expect(script).To(ContainSubstring(fmt.Sprintf(`headers:add("x-canary-version", rand >= %d and "stable" or "canary")`, ratio)))
+		},
+		Entry("no canary", 0),
+		Entry("20 percent", 20),
+		Entry("full canary", 100),
+	)
+
+	It("accumulates commit weights and skips zero weights", func() {
+		svc := newService(meshmanagerv1.CanaryType)
+		svc.CommitHashes = []string{"a", "b", "c"}
+		svc.Weights = []meshmanagerv1.CommitWeight{
+			{CommitHash: "a", Weight: 50},
+			{CommitHash: "b", Weight: 0},
+			{CommitHash: "c", Weight: 50},
+		}
+
+		script := luaScripts(GenerateEnvoyFilter(svc, testRoute))[0]
+		Expect(script).To(ContainSubstring(`{ commit = "a", upper = 50 },`))
+		Expect(script).To(ContainSubstring(`{ commit = "c", upper = 100 },`))
+		Expect(script).NotTo(ContainSubstring(`commit = "b"`))
+		Expect(script).To(ContainSubstring(`headers:add("x-canary-version", pick_version(rand))`))
+	})
+
+	It("uses VirtualService weights without an EnvoyFilter in Weighted mode", func() {
+		svc := newService(meshmanagerv1.CanaryType)
+		svc.RoutingMode = meshmanagerv1.WeightedRoutingMode
+		Expect(NeedsEnvoyFilter(svc)).To(BeFalse())
+
+		vs, err := GenerateVirtualService(svc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vs.Spec.Http).To(HaveLen(1))
+		Expect(vs.Spec.Http[0].Route).To(HaveLen(2))
+		Expect(vs.Spec.Http[0].Route[0].Destination.Subset).To(Equal("stable"))
+		Expect(vs.Spec.Http[0].Route[0].Weight).To(BeEquivalentTo(80))
+		Expect(vs.Spec.Http[0].Route[1].Destination.Subset).To(Equal("canary"))
+		Expect(vs.Spec.Http[0].Route[1].Weight).To(BeEquivalentTo(20))
+	})
+})
+
+var _ = Describe("Mesh VirtualService", func() {
+	DescribeTable("appends a default route for requests without x-canary-version",
+		func(fallback, expected string) {
+			svc := newService(meshmanagerv1.CanaryType)
+			svc.FallbackCommitHash = fallback
+
+			vs, err := GenerateVirtualService(svc)
+			Expect(err).NotTo(HaveOccurred())
+			last := vs.Spec.Http[len(vs.Spec.Http)-1]
+			Expect(last.Match).To(BeEmpty())
+			Expect(last.Route[0].Destination.Subset).To(Equal(expected))
+		},
+		Entry("first commit by default", "", "stable"),
+		Entry("configured fallback commit", "canary", "canary"),
+	)
+
+	It("falls back to the default commit when dependency headers are missing", func() {
+		svc := newService(meshmanagerv1.StandardType)
+		svc.Dependencies = []meshmanagerv1.Dependency{{Name: "order", CommitHashes: []string{"v2"}}}
+
+		vs, err := GenerateVirtualService(svc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vs.Spec.Http).To(HaveLen(2))
+		Expect(vs.Spec.Http[0].Match[0].Headers["x-order-version"].GetExact()).To(Equal("v2"))
+		Expect(vs.Spec.Http[1].Match).To(BeEmpty())
+		Expect(vs.Spec.Http[1].Route[0].Destination.Subset).To(Equal("stable"))
+	})
+
+	DescribeTable("scopes fault injection to the configured commit",
+		func(commit string, faulted []string) {
+			svc := newService(meshmanagerv1.CanaryType)
+			svc.Fault = &meshmanagerv1.FaultInjection{
+				CommitHash: commit,
+				Abort:      &meshmanagerv1.FaultAbort{HTTPStatus: 503, Percentage: 10},
+			}
+
+			vs, err := GenerateVirtualService(svc)
+			Expect(err).NotTo(HaveOccurred())
+			// stable 헤더 라우트, canary 헤더 라우트, 기본 라우트(stable) 순
+			Expect(vs.Spec.Http).To(HaveLen(3))
+			var got []string
+			for _, route := range vs.Spec.Http {
+				if route.Fault != nil {
+					Expect(route.Fault.Abort.GetHttpStatus()).To(BeEquivalentTo(503))
+					got = append(got, route.Route[0].Destination.Subset)
+				}
+			}
+			Expect(got).To(Equal(faulted))
+		},
+		Entry("every route", "", []string{"stable", "canary", "stable"}),
+		Entry("canary only", "canary", []string{"canary"}),
+	)
+})
+
+var _ = Describe("DestinationRule", func() {
+	DescribeTable("applies outlier detection to every service type",
+		func(serviceType meshmanagerv1.ServiceType) {
+			svc := newService(serviceType)
+			svc.OutlierDetection = &meshmanagerv1.OutlierDetection{
+				Consecutive5xxErrors: intPtr(7),
+				Interval:             "10s",
+				BaseEjectionTime:     "1m",
+				MaxEjectionPercent:   int32Ptr(30),
+				MinHealthPercent:     int32Ptr(0),
+			}
+
+			dr, err := GenerateDestinationRule(svc)
+			Expect(err).NotTo(HaveOccurred())
+			outlier := dr.Spec.TrafficPolicy.OutlierDetection
+			Expect(outlier.Consecutive_5XxErrors.GetValue()).To(BeEquivalentTo(7))
+			Expect(outlier.Interval.AsDuration()).To(Equal(10 * time.Second))
+			Expect(outlier.BaseEjectionTime.AsDuration()).To(Equal(time.Minute))
+			Expect(outlier.MaxEjectionPercent).To(BeEquivalentTo(30))
+			// 0 도 명시적으로 지정한 값이므로 그대로 전달
+			Expect(outlier.MinHealthPercent).To(BeZero())
+		},
+		Entry("standard", meshmanagerv1.StandardType),
+		Entry("canary", meshmanagerv1.CanaryType),
+		Entry("sticky canary", meshmanagerv1.StickyCanaryType),
+	)
+
+	DescribeTable("sends consecutive5xxErrors only when it is set",
+		func(value *int, sent bool, expected int) {
+			svc := newService(meshmanagerv1.CanaryType)
+			svc.OutlierDetection = &meshmanagerv1.OutlierDetection{Consecutive5xxErrors: value, Interval: "10s"}
+
+			dr, err := GenerateDestinationRule(svc)
+			Expect(err).NotTo(HaveOccurred())
+			errors := dr.Spec.TrafficPolicy.OutlierDetection.Consecutive_5XxErrors
+			if !sent {
+				Expect(errors).To(BeNil())
+				return
+			}
+			Expect(errors).NotTo(BeNil())
+			Expect(errors.GetValue()).To(BeEquivalentTo(expected))
+		},
+		Entry("unset keeps the Istio default", nil, false, 0),
+		Entry("explicit zero disables it", intPtr(0), true, 0),
+		Entry("explicit value", intPtr(7), true, 7),
+	)
+
+	It("rejects an invalid outlier detection interval", func() {
+		svc := newService(meshmanagerv1.CanaryType)
+		svc.OutlierDetection = &meshmanagerv1.OutlierDetection{Interval: "soon"}
+
+		_, err := GenerateDestinationRule(svc)
+		Expect(err).To(MatchError(ContainSubstring("outlierDetection.interval")))
+	})
+
+	It("applies subset traffic policies only to the commits that still exist", func() {
+		svc := newService(meshmanagerv1.CanaryType)
+		svc.TrafficPolicy = &meshmanagerv1.TrafficPolicy{
+			ConnectionPool: &meshmanagerv1.ConnectionPool{MaxConnections: 100, MaxRequests: 50, IdleTimeout: "30s"},
+			LoadBalancer:   meshmanagerv1.LeastRequestLoadBalancer,
+			Subsets: []meshmanagerv1.SubsetTrafficPolicy{
+				{CommitHash: "canary", ConnectionPool: &meshmanagerv1.ConnectionPool{MaxRequests: 10}},
+				{CommitHash: "removed", ConnectionPool: &meshmanagerv1.ConnectionPool{MaxRequests: 1}},
+			},
+		}
+
+		dr, err := GenerateDestinationRule(svc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dr.Spec.TrafficPolicy.ConnectionPool.Http.Http2MaxRequests).To(BeEquivalentTo(50))
+		Expect(dr.Spec.TrafficPolicy.LoadBalancer.GetSimple().String()).To(Equal("LEAST_REQUEST"))
+
+		Expect(dr.Spec.Subsets).To(HaveLen(2))
+		Expect(dr.Spec.Subsets[0].TrafficPolicy).To(BeNil())
+		// 서브셋에서 지정하지 않은 항목은 서비스 단위 설정을 따름
+		pool := dr.Spec.Subsets[1].TrafficPolicy.ConnectionPool
+		Expect(pool.Tcp.MaxConnections).To(BeEquivalentTo(100))
+		Expect(pool.Http.Http2MaxRequests).To(BeEquivalentTo(10))
+		Expect(pool.Http.IdleTimeout.AsDuration()).To(Equal(30 * time.Second))
+	})
+})
@@ -0,0 +1,156 @@
+package generators
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	apiv1beta1 "istio.io/api/networking/v1beta1"
+)
+
+// ingressSettings 미지정 시 기존 동작(/<서비스명> prefix, /api rewrite, 모든 호스트)
+func ingressSettings(svc meshmanagerv1.ServiceConfig) meshmanagerv1.IngressConfig {
+	var ingress meshmanagerv1.IngressConfig
+	if svc.Ingress != nil {
+		ingress = *svc.Ingress.DeepCopy()
+	}
+	ingress.SetDefaults(svc.Name)
+	return ingress
+}
+
+func ingressHosts(svc meshmanagerv1.ServiceConfig) []string {
+	if hosts := ingressSettings(svc).Hosts; len(hosts) > 0 {
+		return hosts
+	}
+	return []string{"*"}
+}
+
+// trimPrefixPath 세그먼트 비교를 위해 끝의 / 제거. 루트는 빈 문자열
+func trimPrefixPath(path string) string {
+	return strings.TrimRight(path, "/")
+}
+
+// ingressURIMatch -ingress VirtualService 의 uri 매칭
+func ingressURIMatch(svc meshmanagerv1.ServiceConfig) *apiv1beta1.StringMatch {
+	ingress := ingressSettings(svc)
+	return pathMatch(ingress.PathType, ingress.Path)
+}
+
+// ingressRewrite 매칭 방식에 맞는 rewrite. rewrite 를 끈 경우 nil
+func ingressRewrite(svc meshmanagerv1.ServiceConfig) *apiv1beta1.HTTPRewrite {
+	ingress := ingressSettings(svc)
+	return pathRewrite(ingress.PathType, ingress.Path, *ingress.Rewrite)
+}
+
+// pathMatch Prefix 는 /user 가 /users 에 매칭되지 않도록 정규식으로 세그먼트 경계까지 확인
+func pathMatch(pathType meshmanagerv1.IngressPathType, path string) *apiv1beta1.StringMatch {
+	switch pathType {
+	case meshmanagerv1.IngressPathExact:
+		return &apiv1beta1.StringMatch{MatchType: &apiv1beta1.StringMatch_Exact{Exact: path}}
+	case meshmanagerv1.IngressPathRegex:
+		return &apiv1beta1.StringMatch{MatchType: &apiv1beta1.StringMatch_Regex{Regex: path}}
+	}
+
+	prefix := trimPrefixPath(path)
+	if prefix == "" {
+		return &apiv1beta1.StringMatch{MatchType: &apiv1beta1.StringMatch_Prefix{Prefix: "/"}}
+	}
+	return &apiv1beta1.StringMatch{
+		MatchType: &apiv1beta1.StringMatch_Regex{Regex: fmt.Sprintf("^%s(/.*)?$", regexp.QuoteMeta(prefix))},
+	}
+}
+
+// pathRewrite Exact/Regex 는 경로 전체를, Prefix 는 매칭된 prefix 부분만 target 으로 교체
+func pathRewrite(pathType meshmanagerv1.IngressPathType, path, target string) *apiv1beta1.HTTPRewrite {
+	if target == "" {
+		return nil
+	}
+	if pathType != meshmanagerv1.IngressPathPrefix {
+		return &apiv1beta1.HTTPRewrite{Uri: target}
+	}
+
+	prefix := trimPrefixPath(path)
+	if prefix == "" {
+		return &apiv1beta1.HTTPRewrite{Uri: target}
+	}
+
+	// 세그먼트 정규식으로 매칭했으므로 rewrite 도 정규식으로 (/user/items -> /api/items)
+	target = trimPrefixPath(target)
+	if target == "" {
+		return &apiv1beta1.HTTPRewrite{
+			UriRegexRewrite: &apiv1beta1.RegexRewrite{
+				Match:   fmt.Sprintf("^%s/?(.*)$", regexp.QuoteMeta(prefix)),
+				Rewrite: `/\1`,
+			},
+		}
+	}
+	return &apiv1beta1.HTTPRewrite{
+		UriRegexRewrite: &apiv1beta1.RegexRewrite{
+			Match:   fmt.Sprintf("^%s(/.*)?$", regexp.QuoteMeta(prefix)),
+			Rewrite: target + `\1`,
+		},
+	}
+}
+
+// ingressMatchLua Lua 필터가 ingress VirtualService 와 같은 호스트/경로에서만 동작하도록 하는 조건식.
+// 같은 경로를 다른 호스트로 노출한 서비스의 필터가 서로의 요청에 헤더를 붙이지 않도록 호스트도 확인
+func ingressMatchLua(svc meshmanagerv1.ServiceConfig) string {
+	hostCond := ingressHostLua(svc)
+	if hostCond == "" {
+		return ingressPathLua(svc)
+	}
+	return fmt.Sprintf("(%s) and (%s)", hostCond, ingressPathLua(svc))
+}
+
+// ingressHostLua host 변수(소문자, 포트 제외 :authority) 기준 조건식. 모든 호스트를 허용하면 빈 문자열
+func ingressHostLua(svc meshmanagerv1.ServiceConfig) string {
+	var conds []string
+	for _, host := range ingressHosts(svc) {
+		host = strings.ToLower(host)
+		switch {
+		case host == "*":
+			return ""
+		case strings.HasPrefix(host, "*."):
+			// VirtualService 와 같이 *.example.com 은 하위 도메인만 매칭
+			conds = append(conds, fmt.Sprintf(`string.find(host, %s)`, luaQuote("^.+"+luaPatternEscape(host[1:])+"$")))
+		default:
+			conds = append(conds, fmt.Sprintf(`host == %s`, luaQuote(host)))
+		}
+	}
+	return strings.Join(conds, " or ")
+}
+
+// ingressPathLua path 변수 기준 경로 조건식
+func ingressPathLua(svc meshmanagerv1.ServiceConfig) string {
+	ingress := ingressSettings(svc)
+
+	switch ingress.PathType {
+	case meshmanagerv1.IngressPathExact:
+		return fmt.Sprintf(`string.match(path, "^[^?]*") == %s`, luaQuote(ingress.Path))
+	case meshmanagerv1.IngressPathRegex:
+		// Lua 에서는 RE2 를 쓸 수 없으므로 리터럴 prefix 로만 거르고 정확한 매칭은 VirtualService 에 맡김
+		prefix := regexLiteralPrefix(ingress.Path)
+		if prefix == "" {
+			return "true"
+		}
+		return fmt.Sprintf(`string.find(path, %s)`, luaQuote("^"+luaPatternEscape(prefix)))
+	}
+
+	prefix := trimPrefixPath(ingress.Path)
+	if prefix == "" {
+		return `string.find(path, "^/")`
+	}
+	return fmt.Sprintf(`path == %s or string.find(path, %s)`,
+		luaQuote(prefix), luaQuote("^"+luaPatternEscape(prefix)+"[/?]"))
+}
+
+// regexLiteralPrefix 정규식에 매칭되는 모든 경로가 공통으로 시작하는 문자열
+func regexLiteralPrefix(expr string) string {
+	re, err := regexp.Compile(strings.TrimPrefix(expr, "^"))
+	if err != nil {
+		return ""
+	}
+	prefix, _ := re.LiteralPrefix()
+	return prefix
+}
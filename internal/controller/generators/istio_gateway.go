@@ -1,31 +1,64 @@
 package generators
 
 import (
+	"fmt"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
 	apiv1alpha3 "istio.io/api/networking/v1alpha3"
 	istiov1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func GenerateIstioGateway(gwName, namespace string) *istiov1alpha3.Gateway {
+// gatewaySettings 미지정 시 기존 게이트웨이(default/istio-gateway)와 동일한 설정
+func gatewaySettings(ir *meshmanagerv1.IstioRoute) meshmanagerv1.GatewayConfig {
+	var gw meshmanagerv1.GatewayConfig
+	if ir != nil && ir.Spec.Gateway != nil {
+		gw = *ir.Spec.Gateway.DeepCopy()
+	}
+	gw.SetDefaults()
+	return gw
+}
+
+// GatewayRef ingress VirtualService 의 gateways 항목 (<namespace>/<name>)
+func GatewayRef(ir *meshmanagerv1.IstioRoute) string {
+	gw := gatewaySettings(ir)
+	return fmt.Sprintf("%s/%s", gw.Namespace, gw.Name)
+}
+
+func GenerateIstioGateway(ir *meshmanagerv1.IstioRoute) *istiov1alpha3.Gateway {
+	gw := gatewaySettings(ir)
+
+	var servers []*apiv1alpha3.Server
+	for _, s := range gw.Servers {
+		server := &apiv1alpha3.Server{
+			Port: &apiv1alpha3.Port{
+				Number:   uint32(s.Port),
+				Name:     s.Name,
+				Protocol: string(s.Protocol),
+			},
+			Hosts: s.Hosts,
+		}
+
+		switch {
+		case s.Protocol == meshmanagerv1.GatewayHTTPS:
+			server.Tls = &apiv1alpha3.ServerTLSSettings{
+				Mode:           apiv1alpha3.ServerTLSSettings_SIMPLE,
+				CredentialName: s.CredentialName,
+			}
+		case s.HTTPSRedirect:
+			server.Tls = &apiv1alpha3.ServerTLSSettings{HttpsRedirect: true}
+		}
+		servers = append(servers, server)
+	}
+
 	return &istiov1alpha3.Gateway{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      gwName,
-			Namespace: namespace,
+			Name:      gw.Name,
+			Namespace: gw.Namespace,
 		},
 		Spec: apiv1alpha3.Gateway{
-			Selector: map[string]string{
-				"istio": "ingressgateway",
-			},
-			Servers: []*apiv1alpha3.Server{
-				{
-					Port: &apiv1alpha3.Port{
-						Number:   80,
-						Name:     "http",
-						Protocol: "HTTP",
-					},
-					Hosts: []string{"*"},
-				},
-			},
+			Selector: gw.Selector,
+			Servers:  servers,
 		},
 	}
 }
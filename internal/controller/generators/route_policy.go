@@ -0,0 +1,87 @@
+package generators
+
+import (
+	"fmt"
+	"time"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+	apiv1beta1 "istio.io/api/networking/v1beta1"
+)
+
+// applyRoutePolicies 타임아웃/재시도/오류 주입을 생성된 라우트에 적용
+func applyRoutePolicies(svc meshmanagerv1.ServiceConfig, routes []*apiv1beta1.HTTPRoute) error {
+	var timeout *durationpb.Duration
+	if svc.Timeout != "" {
+		d, err := time.ParseDuration(svc.Timeout)
+		if err != nil {
+			return fmt.Errorf("timeout %q: %w", svc.Timeout, err)
+		}
+		timeout = durationpb.New(d)
+	}
+
+	var retries *apiv1beta1.HTTPRetry
+	if svc.Retries != nil {
+		retries = &apiv1beta1.HTTPRetry{
+			Attempts: int32(svc.Retries.Attempts),
+			RetryOn:  svc.Retries.RetryOn,
+		}
+		if svc.Retries.PerTryTimeout != "" {
+			d, err := time.ParseDuration(svc.Retries.PerTryTimeout)
+			if err != nil {
+				return fmt.Errorf("retries.perTryTimeout %q: %w", svc.Retries.PerTryTimeout, err)
+			}
+			retries.PerTryTimeout = durationpb.New(d)
+		}
+	}
+
+	var fault *apiv1beta1.HTTPFaultInjection
+	if svc.Fault != nil {
+		var err error
+		if fault, err = faultInjection(svc.Fault); err != nil {
+			return err
+		}
+	}
+
+	for _, route := range routes {
+		route.Timeout = timeout
+		route.Retries = retries
+		if fault != nil && faultApplies(svc.Fault, route) {
+			route.Fault = fault
+		}
+	}
+	return nil
+}
+
+func faultInjection(config *meshmanagerv1.FaultInjection) (*apiv1beta1.HTTPFaultInjection, error) {
+	fault := &apiv1beta1.HTTPFaultInjection{}
+
+	if config.Delay != nil {
+		d, err := time.ParseDuration(config.Delay.FixedDelay)
+		if err != nil {
+			return nil, fmt.Errorf("fault.delay.fixedDelay %q: %w", config.Delay.FixedDelay, err)
+		}
+		fault.Delay = &apiv1beta1.HTTPFaultInjection_Delay{
+			HttpDelayType: &apiv1beta1.HTTPFaultInjection_Delay_FixedDelay{FixedDelay: durationpb.New(d)},
+			Percentage:    &apiv1beta1.Percent{Value: float64(config.Delay.Percentage)},
+		}
+	}
+	if config.Abort != nil {
+		fault.Abort = &apiv1beta1.HTTPFaultInjection_Abort{
+			ErrorType:  &apiv1beta1.HTTPFaultInjection_Abort_HttpStatus{HttpStatus: int32(config.Abort.HTTPStatus)},
+			Percentage: &apiv1beta1.Percent{Value: float64(config.Abort.Percentage)},
+		}
+	}
+
+	return fault, nil
+}
+
+// faultApplies CommitHash 가 지정되면 해당 서브셋으로만 가는 라우트에만 적용.
+// 여러 서브셋으로 나눠 보내는 라우트는 특정 커밋만 골라낼 수 없으므로 제외
+func faultApplies(config *meshmanagerv1.FaultInjection, route *apiv1beta1.HTTPRoute) bool {
+	if config.CommitHash == "" {
+		return true
+	}
+	return len(route.Route) == 1 && route.Route[0].Destination != nil &&
+		route.Route[0].Destination.Subset == config.CommitHash
+}
@@ -0,0 +1,221 @@
+package generators
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	apiv1beta1 "istio.io/api/networking/v1alpha3"
+)
+
+// JWT payload 디코딩용 (base64url)
+const luaBase64Decode = `
+local function b64decode(data)
+	local b = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	data = string.gsub(data, "%-", "+")
+	data = string.gsub(data, "_", "/")
+	data = string.gsub(data, "[^" .. b .. "=]", "")
+	return (data:gsub(".", function(x)
+		if x == "=" then return "" end
+		local r, f = "", (b:find(x, 1, true) - 1)
+		for i = 6, 1, -1 do r = r .. (f % 2 ^ i - f % 2 ^ (i - 1) > 0 and "1" or "0") end
+		return r
+	end):gsub("%d%d%d?%d?%d?%d?%d?%d?", function(x)
+		if #x ~= 8 then return "" end
+		local c = 0
+		for i = 1, 8 do c = c + (x:sub(i, i) == "1" and 2 ^ (8 - i) or 0) end
+		return string.char(c)
+	end))
+end
+`
+
+// stickySession 미지정 시 기존 동작(jwt 헤더, 키 없으면 기존 커밋)
+func stickySession(svc meshmanagerv1.ServiceConfig) meshmanagerv1.StickySession {
+	session := meshmanagerv1.StickySession{
+		Source: meshmanagerv1.StickyHeaderSource,
+		Name:   meshmanagerv1.DefaultStickyHeader,
+	}
+	if svc.StickySession != nil {
+		session = *svc.StickySession
+	}
+	session.SetDefaults()
+	return session
+}
+
+// stickyLuaHelpers envoy_on_request 밖에 정의할 함수
+func stickyLuaHelpers(svc meshmanagerv1.ServiceConfig) string {
+	helpers := versionLuaHelpers(svc)
+	if stickySession(svc).Source == meshmanagerv1.StickyJWTClaimSource {
+		helpers += luaBase64Decode
+	}
+	return helpers
+}
+
+// stickyKeyLua 설정된 소스에서 key 변수를 채우는 Lua 코드 (들여쓰기 2탭 기준)
+func stickyKeyLua(svc meshmanagerv1.ServiceConfig) string {
+	session := stickySession(svc)
+
+	switch session.Source {
+	case meshmanagerv1.StickyCookieSource:
+		return fmt.Sprintf(`		local key = nil
+		local cookie = headers:get("cookie")
+		if cookie then
+			for k, v in string.gmatch(cookie, "([^=;%%s]+)=([^;]*)") do
+				if k == "%s" then
+					key = v
+					break
+				end
+			end
+		end`, session.Name)
+	case meshmanagerv1.StickyJWTClaimSource:
+		claim := luaPatternEscape(session.Name)
+		return fmt.Sprintf(`		local key = nil
+		local token = headers:get("%s")
+		if token then
+			token = string.gsub(token, "^[Bb]earer%%s+", "")
+			local payload = string.match(token, "^[^.]+%%.([^.]+)")
+			if payload then
+				local claims = b64decode(payload)
+				key = string.match(claims, '"%s"%%s*:%%s*"([^"]*)"') or string.match(claims, '"%s"%%s*:%%s*([%%d%%.%%-]+)')
+			end
+		end`, session.TokenHeader, claim, claim)
+	case meshmanagerv1.StickyClientIPSource:
+		return `		local key = headers:get("x-envoy-external-address")
+		if not key then
+			local remote = request_handle:streamInfo():downstreamRemoteAddress()
+			if remote then
+				key = string.match(remote, "^%[(.+)%]:%d+$") or string.match(remote, "^([^:]+):%d+$") or remote
+			end
+		end`
+	default:
+		return fmt.Sprintf(`		local key = headers:get("%s")`, session.Name)
+	}
+}
+
+// 세션 쿠키로 고정된 버전을 envoy_on_response 로 넘길 때 사용하는 dynamic metadata 네임스페이스
+const stickyMetadataNamespace = "meshmanager.canary"
+
+// stickyCookieName SessionDuration 동안 버전을 고정하는 쿠키 이름
+func stickyCookieName(svc meshmanagerv1.ServiceConfig) string {
+	return fmt.Sprintf("%s-canary-version", svc.Name)
+}
+
+// stickyAffinityCookieName DestinationRule 해시 키로 Envoy 가 직접 발급하는 쿠키 이름.
+// 값이 해시이므로 Lua 가 커밋을 기록하는 stickyCookieName 과 겹치지 않게 분리
+func stickyAffinityCookieName(svc meshmanagerv1.ServiceConfig) string {
+	return fmt.Sprintf("%s-canary-affinity", svc.Name)
+}
+
+// stickyVersionLua 세션 쿠키가 있으면 그 버전을 유지하고, 없으면 key 로 버전을 정함.
+// key 도 없으면 Fallback 에 따라 버전 결정
+func stickyVersionLua(svc meshmanagerv1.ServiceConfig) string {
+	fallback := fmt.Sprintf(`version = "%s"`, svc.CommitHashes[0])
+	if stickySession(svc).Fallback == meshmanagerv1.StickyFallbackRandom {
+		fallback = fmt.Sprintf(`version = %s`, versionPickExpr(svc, "math.random(0, 99)"))
+	}
+
+	// 쿠키 값이 현재 커밋 중 하나일 때만 사용
+	var pinned, remember string
+	if svc.SessionDuration > 0 {
+		known := make([]string, len(svc.CommitHashes))
+		for i, hash := range svc.CommitHashes {
+			known[i] = fmt.Sprintf(`pinned == "%s"`, hash)
+		}
+		pinned = fmt.Sprintf(`
+		local cookies = headers:get("cookie")
+		if cookies then
+			local pinned = string.match("; " .. cookies, ";%%s*%s=([^;]+)")
+			if %s then
+				version = pinned
+			end
+		end`, luaPatternEscape(stickyCookieName(svc)), strings.Join(known, " or "))
+		remember = fmt.Sprintf(`
+			request_handle:streamInfo():dynamicMetadata():set("%s", "%s", version)`, stickyMetadataNamespace, svc.Name)
+	}
+
+	return fmt.Sprintf(`		local version = nil%s
+		if not version then
+			if key and key ~= "" then
+				local hash = 0
+				for i = 1, #key do
+					hash = (hash * 31 + key:byte(i)) %% 100
+				end
+				version = %s
+				headers:add("x-session-id", tostring(math.floor(hash)))
+			else
+				%s
+			end%s
+		end
+		headers:add("x-canary-version", version)`, pinned, versionPickExpr(svc, "hash"), fallback, remember)
+}
+
+// stickyResponseLua 새로 정한 버전을 SessionDuration 만큼 유지하도록 쿠키 발급
+func stickyResponseLua(svc meshmanagerv1.ServiceConfig) string {
+	if svc.SessionDuration <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(`
+
+function envoy_on_response(response_handle)
+	local meta = response_handle:streamInfo():dynamicMetadata():get("%s")
+	if meta and meta["%s"] then
+		response_handle:headers():add("set-cookie", "%s=" .. meta["%s"] .. "; Max-Age=%d; Path=/; HttpOnly")
+	end
+end`, stickyMetadataNamespace, svc.Name, stickyCookieName(svc), svc.Name, svc.SessionDuration)
+}
+
+// stickyHashKey DestinationRule 의 ConsistentHash 키도 같은 소스를 따르도록 설정
+func stickyHashKey(svc meshmanagerv1.ServiceConfig) *apiv1beta1.LoadBalancerSettings_ConsistentHashLB {
+	session := stickySession(svc)
+
+	lb := &apiv1beta1.LoadBalancerSettings_ConsistentHashLB{}
+
+	// SessionDuration 을 지정한 경우에만 같은 TTL 의 별도 쿠키로 해시
+	if svc.SessionDuration > 0 {
+		lb.HashKey = &apiv1beta1.LoadBalancerSettings_ConsistentHashLB_HttpCookie{
+			HttpCookie: &apiv1beta1.LoadBalancerSettings_ConsistentHashLB_HTTPCookie{
+				Name: stickyAffinityCookieName(svc),
+				Path: "/",
+				Ttl:  durationpb.New(time.Duration(svc.SessionDuration) * time.Second),
+			},
+		}
+		return lb
+	}
+
+	switch session.Source {
+	case meshmanagerv1.StickyHeaderSource:
+		lb.HashKey = &apiv1beta1.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{
+			HttpHeaderName: session.Name,
+		}
+	case meshmanagerv1.StickyCookieSource:
+		lb.HashKey = &apiv1beta1.LoadBalancerSettings_ConsistentHashLB_HttpCookie{
+			HttpCookie: &apiv1beta1.LoadBalancerSettings_ConsistentHashLB_HTTPCookie{Name: session.Name},
+		}
+	case meshmanagerv1.StickyClientIPSource:
+		lb.HashKey = &apiv1beta1.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{
+			UseSourceIp: true,
+		}
+	default:
+		// JWT claim 은 게이트웨이 Lua 가 계산한 x-session-id 사용
+		lb.HashKey = &apiv1beta1.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{
+			HttpHeaderName: "x-session-id",
+		}
+	}
+	return lb
+}
+
+// luaPatternEscape Lua 패턴 특수문자 이스케이프
+func luaPatternEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("^$()%.[]*+-?", r) {
+			b.WriteRune('%')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
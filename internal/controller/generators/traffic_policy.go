@@ -0,0 +1,127 @@
+package generators
+
+import (
+	"fmt"
+	"time"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+	apiv1beta1 "istio.io/api/networking/v1beta1"
+	istiov1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+)
+
+// addTrafficPolicy 서비스 전체 설정을 적용한 뒤 서브셋별 설정을 덮어씀.
+// 이미 설정된 로드밸런서(StickyCanary 의 ConsistentHash)는 유지
+func addTrafficPolicy(dr *istiov1beta1.DestinationRule, config *meshmanagerv1.TrafficPolicy) error {
+	if dr.Spec.TrafficPolicy == nil {
+		dr.Spec.TrafficPolicy = &apiv1beta1.TrafficPolicy{}
+	}
+	if err := applyTrafficPolicy(dr.Spec.TrafficPolicy, config.ConnectionPool, config.LoadBalancer); err != nil {
+		return err
+	}
+
+	for _, override := range config.Subsets {
+		// 롤백/전환으로 사라진 커밋의 설정은 무시
+		for _, subset := range dr.Spec.Subsets {
+			if subset.Name != override.CommitHash {
+				continue
+			}
+			if subset.TrafficPolicy == nil {
+				subset.TrafficPolicy = &apiv1beta1.TrafficPolicy{}
+			}
+			pool := mergeConnectionPool(config.ConnectionPool, override.ConnectionPool)
+			if err := applyTrafficPolicy(subset.TrafficPolicy, pool, override.LoadBalancer); err != nil {
+				return fmt.Errorf("subset %s: %w", override.CommitHash, err)
+			}
+		}
+	}
+	return nil
+}
+
+func applyTrafficPolicy(policy *apiv1beta1.TrafficPolicy, pool *meshmanagerv1.ConnectionPool, lb meshmanagerv1.LoadBalancerType) error {
+	if pool != nil {
+		settings, err := connectionPoolSettings(pool)
+		if err != nil {
+			return err
+		}
+		policy.ConnectionPool = settings
+	}
+
+	if lb != "" && policy.LoadBalancer == nil {
+		simple, err := simpleLoadBalancer(lb)
+		if err != nil {
+			return err
+		}
+		policy.LoadBalancer = &apiv1beta1.LoadBalancerSettings{
+			LbPolicy: &apiv1beta1.LoadBalancerSettings_Simple{Simple: simple},
+		}
+	}
+	return nil
+}
+
+// mergeConnectionPool Istio 는 서브셋의 connectionPool 을 통째로 교체하므로
+// 서브셋에서 지정하지 않은 항목은 서비스 전체 설정으로 채움
+func mergeConnectionPool(base, override *meshmanagerv1.ConnectionPool) *meshmanagerv1.ConnectionPool {
+	if override == nil || base == nil {
+		return override
+	}
+
+	merged := *base
+	if override.MaxConnections > 0 {
+		merged.MaxConnections = override.MaxConnections
+	}
+	if override.MaxPendingRequests > 0 {
+		merged.MaxPendingRequests = override.MaxPendingRequests
+	}
+	if override.MaxRequests > 0 {
+		merged.MaxRequests = override.MaxRequests
+	}
+	if override.MaxRequestsPerConnection > 0 {
+		merged.MaxRequestsPerConnection = override.MaxRequestsPerConnection
+	}
+	if override.IdleTimeout != "" {
+		merged.IdleTimeout = override.IdleTimeout
+	}
+	return &merged
+}
+
+func connectionPoolSettings(pool *meshmanagerv1.ConnectionPool) (*apiv1beta1.ConnectionPoolSettings, error) {
+	settings := &apiv1beta1.ConnectionPoolSettings{}
+
+	if pool.MaxConnections > 0 {
+		settings.Tcp = &apiv1beta1.ConnectionPoolSettings_TCPSettings{
+			MaxConnections: int32(pool.MaxConnections),
+		}
+	}
+
+	http := &apiv1beta1.ConnectionPoolSettings_HTTPSettings{
+		Http1MaxPendingRequests:  int32(pool.MaxPendingRequests),
+		Http2MaxRequests:         int32(pool.MaxRequests),
+		MaxRequestsPerConnection: int32(pool.MaxRequestsPerConnection),
+	}
+	if pool.IdleTimeout != "" {
+		idleTimeout, err := time.ParseDuration(pool.IdleTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("connectionPool.idleTimeout %q: %w", pool.IdleTimeout, err)
+		}
+		http.IdleTimeout = durationpb.New(idleTimeout)
+	}
+	if pool.MaxPendingRequests > 0 || pool.MaxRequests > 0 || pool.MaxRequestsPerConnection > 0 || http.IdleTimeout != nil {
+		settings.Http = http
+	}
+
+	return settings, nil
+}
+
+func simpleLoadBalancer(lb meshmanagerv1.LoadBalancerType) (apiv1beta1.LoadBalancerSettings_SimpleLB, error) {
+	switch lb {
+	case meshmanagerv1.RoundRobinLoadBalancer:
+		return apiv1beta1.LoadBalancerSettings_ROUND_ROBIN, nil
+	case meshmanagerv1.LeastRequestLoadBalancer:
+		return apiv1beta1.LoadBalancerSettings_LEAST_REQUEST, nil
+	case meshmanagerv1.RandomLoadBalancer:
+		return apiv1beta1.LoadBalancerSettings_RANDOM, nil
+	default:
+		return apiv1beta1.LoadBalancerSettings_UNSPECIFIED, fmt.Errorf("unsupported load balancer %q", lb)
+	}
+}
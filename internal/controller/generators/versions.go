@@ -0,0 +1,137 @@
+package generators
+
+import (
+	"fmt"
+
+	istiov1 "istio.io/client-go/pkg/apis/networking/v1"
+	istiov1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istiov1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NetworkingVersion 생성/감시에 사용할 networking.istio.io API 버전.
+// 세 버전의 spec 은 같은 타입이므로 버전만 바꿔 생성할 수 있음
+type NetworkingVersion string
+
+const (
+	NetworkingV1       NetworkingVersion = "v1"
+	NetworkingV1beta1  NetworkingVersion = "v1beta1"
+	NetworkingV1alpha3 NetworkingVersion = "v1alpha3"
+)
+
+const networkingGroup = "networking.istio.io"
+
+// 최신 버전부터 확인
+var preferredNetworkingVersions = []NetworkingVersion{NetworkingV1, NetworkingV1beta1, NetworkingV1alpha3}
+
+// DiscoverNetworkingVersion 클러스터가 virtualservices 를 제공하는 가장 최신 버전
+func DiscoverNetworkingVersion(dc discovery.DiscoveryInterface) (NetworkingVersion, error) {
+	for _, version := range preferredNetworkingVersions {
+		resources, err := dc.ServerResourcesForGroupVersion(fmt.Sprintf("%s/%s", networkingGroup, version))
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, r := range resources.APIResources {
+			if r.Name == "virtualservices" {
+				return version, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s is not served by the cluster", networkingGroup)
+}
+
+// Versioned 생성한 VirtualService/DestinationRule/Gateway 를 지정한 버전의 타입으로 변환.
+// EnvoyFilter 는 v1alpha3 만 있으므로 그대로 반환
+func Versioned(obj client.Object, version NetworkingVersion) client.Object {
+	switch o := obj.(type) {
+	case *istiov1beta1.VirtualService:
+		switch version {
+		case NetworkingV1:
+			out := &istiov1.VirtualService{ObjectMeta: *o.ObjectMeta.DeepCopy()}
+			o.Spec.DeepCopyInto(&out.Spec)
+			return out
+		case NetworkingV1alpha3:
+			out := &istiov1alpha3.VirtualService{ObjectMeta: *o.ObjectMeta.DeepCopy()}
+			o.Spec.DeepCopyInto(&out.Spec)
+			return out
+		}
+	case *istiov1beta1.DestinationRule:
+		switch version {
+		case NetworkingV1:
+			out := &istiov1.DestinationRule{ObjectMeta: *o.ObjectMeta.DeepCopy()}
+			o.Spec.DeepCopyInto(&out.Spec)
+			return out
+		case NetworkingV1alpha3:
+			out := &istiov1alpha3.DestinationRule{ObjectMeta: *o.ObjectMeta.DeepCopy()}
+			o.Spec.DeepCopyInto(&out.Spec)
+			return out
+		}
+	case *istiov1alpha3.Gateway:
+		switch version {
+		case NetworkingV1:
+			out := &istiov1.Gateway{ObjectMeta: *o.ObjectMeta.DeepCopy()}
+			o.Spec.DeepCopyInto(&out.Spec)
+			return out
+		case NetworkingV1beta1:
+			out := &istiov1beta1.Gateway{ObjectMeta: *o.ObjectMeta.DeepCopy()}
+			o.Spec.DeepCopyInto(&out.Spec)
+			return out
+		}
+	}
+	return obj
+}
+
+// OwnedTypes IstioRoute 하나가 소유하는 리소스 타입 (VirtualService, DestinationRule, EnvoyFilter)
+func OwnedTypes(version NetworkingVersion) []client.Object {
+	envoyFilter := &istiov1alpha3.EnvoyFilter{}
+	switch version {
+	case NetworkingV1:
+		return []client.Object{&istiov1.VirtualService{}, &istiov1.DestinationRule{}, envoyFilter}
+	case NetworkingV1beta1:
+		return []client.Object{&istiov1beta1.VirtualService{}, &istiov1beta1.DestinationRule{}, envoyFilter}
+	default:
+		return []client.Object{&istiov1alpha3.VirtualService{}, &istiov1alpha3.DestinationRule{}, envoyFilter}
+	}
+}
+
+// OwnedListTypes OwnedTypes 의 리스트 타입
+func OwnedListTypes(version NetworkingVersion) []client.ObjectList {
+	envoyFilters := &istiov1alpha3.EnvoyFilterList{}
+	switch version {
+	case NetworkingV1:
+		return []client.ObjectList{&istiov1.VirtualServiceList{}, &istiov1.DestinationRuleList{}, envoyFilters}
+	case NetworkingV1beta1:
+		return []client.ObjectList{&istiov1beta1.VirtualServiceList{}, &istiov1beta1.DestinationRuleList{}, envoyFilters}
+	default:
+		return []client.ObjectList{&istiov1alpha3.VirtualServiceList{}, &istiov1alpha3.DestinationRuleList{}, envoyFilters}
+	}
+}
+
+// GatewayType Gateway 는 여러 IstioRoute 가 같은 이름으로 공유할 수 있어 소유 리소스와 따로 관리
+func GatewayType(version NetworkingVersion) client.Object {
+	switch version {
+	case NetworkingV1:
+		return &istiov1.Gateway{}
+	case NetworkingV1beta1:
+		return &istiov1beta1.Gateway{}
+	default:
+		return &istiov1alpha3.Gateway{}
+	}
+}
+
+// GatewayListType GatewayType 의 리스트 타입
+func GatewayListType(version NetworkingVersion) client.ObjectList {
+	switch version {
+	case NetworkingV1:
+		return &istiov1.GatewayList{}
+	case NetworkingV1beta1:
+		return &istiov1beta1.GatewayList{}
+	default:
+		return &istiov1alpha3.GatewayList{}
+	}
+}
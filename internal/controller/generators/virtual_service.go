@@ -6,10 +6,9 @@ import (
 	apiv1beta1 "istio.io/api/networking/v1beta1"
 	istiov1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"regexp"
 )
 
-func GenerateVirtualService(svc meshmanagerv1.ServiceConfig) *istiov1beta1.VirtualService {
+func GenerateVirtualService(svc meshmanagerv1.ServiceConfig) (*istiov1beta1.VirtualService, error) {
 	vs := &istiov1beta1.VirtualService{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      svc.Name,
@@ -26,6 +25,14 @@ func GenerateVirtualService(svc meshmanagerv1.ServiceConfig) *istiov1beta1.Virtu
 	isDependent := len(svc.Dependencies) > 0
 
 	switch {
+	case baseType == meshmanagerv1.MirrorType:
+		mainRoutes = generateMirrorRoutes(svc)
+	case baseType == meshmanagerv1.BlueGreenType:
+		mainRoutes = generateBlueGreenRoutes(svc)
+	case baseType == meshmanagerv1.ABTestType:
+		mainRoutes = generateABTestRoutes(svc, nil, nil)
+	case weightedRouting(svc):
+		mainRoutes = generateWeightedRoutes(svc)
 	case baseType == meshmanagerv1.CanaryType && isDependent:
 		mainRoutes = generateCanaryDependentRoutes(svc)
 	case baseType == meshmanagerv1.StickyCanaryType && isDependent:
@@ -50,9 +57,12 @@ func GenerateVirtualService(svc meshmanagerv1.ServiceConfig) *istiov1beta1.Virtu
 		allRoutes = mainRoutes
 	}
 
+	if err := applyRoutePolicies(svc, allRoutes); err != nil {
+		return nil, err
+	}
 	vs.Spec.Http = allRoutes
 
-	return vs
+	return vs, nil
 }
 
 func generateCanaryRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
@@ -83,7 +93,8 @@ func generateCanaryRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRou
 		routes = append(routes, route)
 	}
 
-	return routes
+	// 게이트웨이를 거치지 않아 x-canary-version 이 없는 메시 내부 요청용
+	return append(routes, generateStandardRoutes(svc)...)
 }
 
 func generateStickyCanaryRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
@@ -133,7 +144,7 @@ func generateCanaryDependentRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta
 		routes = append(routes, route)
 	}
 
-	return routes
+	return append(routes, generateStandardRoutes(svc)...)
 }
 
 func generateStickyCanaryDependentRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
@@ -169,7 +180,8 @@ func generateDependentRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTP
 		},
 	}
 
-	return []*apiv1beta1.HTTPRoute{route}
+	// 의존성 헤더가 없는 메시 내부 요청도 404 없이 기본 커밋으로 보냄
+	return append([]*apiv1beta1.HTTPRoute{route}, generateStandardRoutes(svc)...)
 }
 
 func generateStandardRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
@@ -188,47 +200,66 @@ func generateStandardRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPR
 	return []*apiv1beta1.HTTPRoute{route}
 }
 
+// generateMirrorRoutes 응답은 기존 커밋에서만 받고 섀도 커밋으로는 복사본만 전송
+func generateMirrorRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
+	routes := generateStandardRoutes(svc)
+	for _, route := range routes {
+		addMirror(svc, route)
+	}
+	return routes
+}
+
+func addMirror(svc meshmanagerv1.ServiceConfig, route *apiv1beta1.HTTPRoute) {
+	if len(svc.CommitHashes) < 2 {
+		return
+	}
+
+	percentage := meshmanagerv1.DefaultMirrorPercentage
+	if svc.Mirror != nil && svc.Mirror.Percentage != nil {
+		percentage = *svc.Mirror.Percentage
+	}
+
+	route.Mirror = &apiv1beta1.Destination{
+		Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
+		Subset: svc.CommitHashes[1],
+	}
+	route.MirrorPercentage = &apiv1beta1.Percent{Value: float64(percentage)}
+}
+
 func generateDarknessReleaseRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
 	var routes []*apiv1beta1.HTTPRoute
 
+	// IP/CIDR, 사용자 ID 매칭은 게이트웨이 Lua 가 하고 VS 는 태깅된 헤더만 확인
 	for _, dr := range svc.DarknessReleases {
-		for _, ip := range dr.IPs {
-			regexPattern := convertSingleIPToRegex(ip)
-
-			route := &apiv1beta1.HTTPRoute{
-				Match: []*apiv1beta1.HTTPMatchRequest{
-					{
-						Headers: map[string]*apiv1beta1.StringMatch{
-							"x-forwarded-for": {
-								MatchType: &apiv1beta1.StringMatch_Regex{
-									Regex: regexPattern,
-								},
-							},
-						},
-					},
-				},
-				Route: []*apiv1beta1.HTTPRouteDestination{
-					{
-						Destination: &apiv1beta1.Destination{
-							Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
-							Subset: dr.CommitHash,
-						},
+		route := &apiv1beta1.HTTPRoute{
+			Match: darknessMatches(dr, nil),
+			Route: []*apiv1beta1.HTTPRouteDestination{
+				{
+					Destination: &apiv1beta1.Destination{
+						Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
+						Subset: dr.CommitHash,
 					},
 				},
-			}
-			routes = append(routes, route)
+			},
 		}
+		routes = append(routes, route)
 	}
 	return routes
 }
 
-// 단일 IP를 XFF 헤더 검사용 정규식으로 변환
-func convertSingleIPToRegex(ip string) string {
-	escapedIP := regexp.QuoteMeta(ip)
-	return fmt.Sprintf(`(^|, )%s(,|$)`, escapedIP)
-}
-
 func getDefaultSubset(svc meshmanagerv1.ServiceConfig) string {
+	if svc.Type == meshmanagerv1.BlueGreenType {
+		active, _ := blueGreenCommits(svc)
+		return active
+	}
+	if svc.Type == meshmanagerv1.ABTestType {
+		return abTestDefaultCommit(svc)
+	}
+
+	if svc.FallbackCommitHash != "" {
+		return svc.FallbackCommitHash
+	}
+
 	darknessHashes := make(map[string]struct{})
 	for _, dr := range svc.DarknessReleases {
 		darknessHashes[dr.CommitHash] = struct{}{}
@@ -242,67 +273,69 @@ func getDefaultSubset(svc meshmanagerv1.ServiceConfig) string {
 	return svc.CommitHashes[0] // fallback
 }
 
-func GenerateIngressVirtualService(svc meshmanagerv1.ServiceConfig) *istiov1beta1.VirtualService {
+// GenerateIngressVirtualService gatewayRef 는 GatewayRef 로 만든 <namespace>/<name>
+func GenerateIngressVirtualService(svc meshmanagerv1.ServiceConfig, gatewayRef string) (*istiov1beta1.VirtualService, error) {
 	vs := &istiov1beta1.VirtualService{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      svc.Name + "-ingress",
 			Namespace: svc.Namespace,
 		},
 		Spec: apiv1beta1.VirtualService{
-			Hosts:    []string{"*"},
-			Gateways: []string{"istio-gateway"},
+			Hosts:    ingressHosts(svc),
+			Gateways: []string{gatewayRef},
 			Http:     generateIngressRoutes(svc),
 		},
 	}
-	return vs
+	if err := applyRoutePolicies(svc, vs.Spec.Http); err != nil {
+		return nil, err
+	}
+	return vs, nil
 }
 
 func generateIngressRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
-	uriPrefix := fmt.Sprintf("/%s", svc.Name) // 서비스명 기반 경로 생성
-
 	var routes []*apiv1beta1.HTTPRoute
 
+	if svc.Type == meshmanagerv1.BlueGreenType {
+		routes = append(routes, generateBlueGreenIngressRoutes(svc)...)
+	}
+
 	for _, dr := range svc.DarknessReleases {
-		for _, ip := range dr.IPs {
-			regexPattern := convertSingleIPToRegex(ip)
-			route := &apiv1beta1.HTTPRoute{
-				Match: []*apiv1beta1.HTTPMatchRequest{{
-					Uri: &apiv1beta1.StringMatch{
-						MatchType: &apiv1beta1.StringMatch_Prefix{Prefix: uriPrefix},
-					},
-					Headers: map[string]*apiv1beta1.StringMatch{
-						"x-envoy-external-address": {
-							MatchType: &apiv1beta1.StringMatch_Regex{
-								Regex: regexPattern,
-							},
-						},
-					},
-				}},
-				Rewrite: &apiv1beta1.HTTPRewrite{Uri: "/api"},
-				Route: []*apiv1beta1.HTTPRouteDestination{{
-					Destination: &apiv1beta1.Destination{
-						Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
-						Subset: dr.CommitHash,
-					},
-				}},
-			}
-			routes = append(routes, route)
+		route := &apiv1beta1.HTTPRoute{
+			Match:   darknessMatches(dr, ingressURIMatch(svc)),
+			Rewrite: ingressRewrite(svc),
+			Route: []*apiv1beta1.HTTPRouteDestination{{
+				Destination: &apiv1beta1.Destination{
+					Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
+					Subset: dr.CommitHash,
+				},
+			}},
 		}
+		routes = append(routes, route)
 	}
 
-	for _, hash := range svc.CommitHashes {
+	// ABTestType 은 규칙 라우트와 기본 라우트로 끝남
+	if svc.Type == meshmanagerv1.ABTestType {
+		return append(routes, generateABTestRoutes(svc, ingressURIMatch(svc), ingressRewrite(svc))...)
+	}
+
+	// MirrorType 은 섀도 커밋으로 직접 가는 라우트 없이 기본 라우트에서 복사하고,
+	// BlueGreenType 은 x-canary-version 대신 preview 라우트를, Weighted 모드는 기본 라우트 가중치를 사용
+	hashes := svc.CommitHashes
+	if svc.Type == meshmanagerv1.MirrorType || svc.Type == meshmanagerv1.BlueGreenType || weightedRouting(svc) {
+		hashes = nil
+	}
+
+	for _, hash := range hashes {
 		route := &apiv1beta1.HTTPRoute{
 			Match: []*apiv1beta1.HTTPMatchRequest{{
-				Uri: &apiv1beta1.StringMatch{
-					MatchType: &apiv1beta1.StringMatch_Prefix{Prefix: uriPrefix},
-				},
+				Uri: ingressURIMatch(svc),
 				Headers: map[string]*apiv1beta1.StringMatch{
 					"x-canary-version": {
 						MatchType: &apiv1beta1.StringMatch_Exact{Exact: hash},
 					},
 				},
 			}},
-			Rewrite: &apiv1beta1.HTTPRewrite{Uri: "/api"},
+			Rewrite: ingressRewrite(svc),
 			Route: []*apiv1beta1.HTTPRouteDestination{{
 				Destination: &apiv1beta1.Destination{
 					Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
@@ -315,11 +348,9 @@ func generateIngressRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRo
 
 	defaultRoute := &apiv1beta1.HTTPRoute{
 		Match: []*apiv1beta1.HTTPMatchRequest{{
-			Uri: &apiv1beta1.StringMatch{
-				MatchType: &apiv1beta1.StringMatch_Prefix{Prefix: uriPrefix},
-			},
+			Uri: ingressURIMatch(svc),
 		}},
-		Rewrite: &apiv1beta1.HTTPRewrite{Uri: "/api"},
+		Rewrite: ingressRewrite(svc),
 		Route: []*apiv1beta1.HTTPRouteDestination{{
 			Destination: &apiv1beta1.Destination{
 				Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
@@ -327,6 +358,12 @@ func generateIngressRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRo
 			},
 		}},
 	}
+	if svc.Type == meshmanagerv1.MirrorType {
+		addMirror(svc, defaultRoute)
+	}
+	if weightedRouting(svc) {
+		defaultRoute.Route = weightedDestinations(svc)
+	}
 	routes = append(routes, defaultRoute)
 
 	return routes
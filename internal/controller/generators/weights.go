@@ -0,0 +1,81 @@
+package generators
+
+import (
+	"fmt"
+	"strings"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	apiv1beta1 "istio.io/api/networking/v1beta1"
+)
+
+// weightedPickLua 0~99 값을 Weights 순서대로 누적한 구간에 맞춰 커밋을 고르는 함수
+func weightedPickLua(svc meshmanagerv1.ServiceConfig) string {
+	weights := svc.CommitWeights()
+
+	var entries []string
+	upper := 0
+	for _, w := range weights {
+		if w.Weight <= 0 {
+			continue
+		}
+		upper += w.Weight
+		entries = append(entries, fmt.Sprintf(`	{ commit = "%s", upper = %d },`, w.CommitHash, upper))
+	}
+
+	return fmt.Sprintf(`
+local version_weights = {
+%s
+}
+
+local function pick_version(value)
+	for _, w in ipairs(version_weights) do
+		if value < w.upper then
+			return w.commit
+		end
+	end
+	return version_weights[#version_weights].commit
+end
+`, strings.Join(entries, "\n"))
+}
+
+// versionLuaHelpers Weights 를 사용할 때만 pick_version 정의
+func versionLuaHelpers(svc meshmanagerv1.ServiceConfig) string {
+	if len(svc.Weights) == 0 {
+		return ""
+	}
+	return weightedPickLua(svc)
+}
+
+// versionPickExpr 0~99 값(value)으로 버전을 고르는 Lua 식.
+// 두 커밋 형식은 value 가 Ratio 미만이면 canary 로, Weighted 모드와 같이 정확히 Ratio% 를 보냄
+func versionPickExpr(svc meshmanagerv1.ServiceConfig, value string) string {
+	if len(svc.Weights) > 0 {
+		return fmt.Sprintf("pick_version(%s)", value)
+	}
+	return fmt.Sprintf(`%s >= %d and "%s" or "%s"`, value, *svc.Ratio, svc.CommitHashes[0], svc.CommitHashes[1])
+}
+
+// weightedRouting Lua 대신 VirtualService 가중치로 분배하는 CanaryType
+func weightedRouting(svc meshmanagerv1.ServiceConfig) bool {
+	return svc.Type == meshmanagerv1.CanaryType && svc.RoutingMode == meshmanagerv1.WeightedRoutingMode
+}
+
+// weightedDestinations 커밋별 비율을 그대로 HTTPRouteDestination 가중치로 사용
+func weightedDestinations(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRouteDestination {
+	var destinations []*apiv1beta1.HTTPRouteDestination
+	for _, w := range svc.CommitWeights() {
+		destinations = append(destinations, &apiv1beta1.HTTPRouteDestination{
+			Destination: &apiv1beta1.Destination{
+				Host:   fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
+				Subset: w.CommitHash,
+			},
+			Weight: int32(w.Weight),
+		})
+	}
+	return destinations
+}
+
+// generateWeightedRoutes x-canary-version 헤더가 없어도 사이드카 간 트래픽까지 비율대로 분배
+func generateWeightedRoutes(svc meshmanagerv1.ServiceConfig) []*apiv1beta1.HTTPRoute {
+	return []*apiv1beta1.HTTPRoute{{Route: weightedDestinations(svc)}}
+}
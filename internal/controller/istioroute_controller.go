@@ -19,16 +19,27 @@ package controller
 import (
 	"context"
 	"fmt"
-	istiov1beta1 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"time"
+
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
 
+	"github.com/MeshManager/MeshManagerAgent/internal/controller/analysis"
 	generator "github.com/MeshManager/MeshManagerAgent/internal/controller/generators"
 )
 
@@ -38,6 +49,19 @@ const EnvoyFilterFinalizer = "meshmanager.com/envoyfilter-cleanup"
 type IstioRouteReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Analyzer ServiceConfig.Analysis 평가용. nil 이면 PROMETHEUS_URL 기반 기본값 사용
+	Analyzer *analysis.Analyzer
+
+	// NetworkingVersion 시작 시 확인한 networking.istio.io 버전. 비어 있으면 v1alpha3
+	NetworkingVersion generator.NetworkingVersion
+}
+
+func (r *IstioRouteReconciler) networkingVersion() generator.NetworkingVersion {
+	if r.NetworkingVersion == "" {
+		return generator.NetworkingV1alpha3
+	}
+	return r.NetworkingVersion
 }
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -60,147 +84,291 @@ func (r *IstioRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	_ = istioRoute.DeepCopy()
+	// 삭제 처리. 리소스를 다시 만들기 전에 이 IstioRoute 소유 리소스만 정리
+	if !istioRoute.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&istioRoute, EnvoyFilterFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.CleanupOwnedResources(ctx, &istioRoute); err != nil {
+			return ctrl.Result{}, r.markFailed(ctx, &istioRoute, nil, err)
+		}
+		controllerutil.RemoveFinalizer(&istioRoute, EnvoyFilterFinalizer)
+		return ctrl.Result{}, r.Update(ctx, &istioRoute)
+	}
+
+	// Finalizer 추가
+	if !controllerutil.ContainsFinalizer(&istioRoute, EnvoyFilterFinalizer) {
+		controllerutil.AddFinalizer(&istioRoute, EnvoyFilterFinalizer)
+		if err := r.Update(ctx, &istioRoute); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
 
-	gateway := generator.GenerateIstioGateway("istio-gateway", "default")
-	if err := ctrl.SetControllerReference(&istioRoute, gateway, r.Scheme); err != nil {
-		return ctrl.Result{}, err
+	// 웹훅이 비활성화된 환경에서도 동일한 기본값으로 생성하도록 복사본에 적용
+	desired := istioRoute.DeepCopy()
+	desired.SetDefaults()
+
+	// 서비스와 마찬가지로 웹훅 없이도 잘못된 Gateway 설정은 재시도 없이 거부
+	if errs := desired.ValidateGateway(); len(errs) > 0 {
+		return ctrl.Result{}, reconcile.TerminalError(r.markFailed(ctx, &istioRoute, nil, errs.ToAggregate()))
+	}
+
+	// 적용한 리소스들의 해시 계산용
+	var rendered []client.Object
+
+	// 클러스터가 제공하는 networking.istio.io 버전으로 생성
+	version := r.networkingVersion()
+
+	// Gateway 는 같은 이름을 쓰는 IstioRoute 들이 공유하므로 설정이 모두 같을 때만 적용하고 소유자는 기록하지 않음
+	if err := r.checkSharedGateway(ctx, desired); err != nil {
+		return ctrl.Result{}, r.markFailed(ctx, &istioRoute, nil, err)
 	}
-	if err := r.CreateOrUpdate(ctx, gateway); err != nil {
+	gateway := generator.Versioned(generator.GenerateIstioGateway(desired), version)
+	setSharedLabels(gateway, gatewayResourceType)
+	if err := r.Apply(ctx, gateway); err != nil {
 		logger.Error(err, "failed to manage Gateway")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, r.markFailed(ctx, &istioRoute, nil, err)
 	}
+	rendered = append(rendered, gateway)
+
+	var svcStatuses []meshmanagerv1.ServiceStatus
+	var requeueAfter time.Duration
+
+	for i, svcConfig := range desired.Spec.Services {
+		svcStatus := meshmanagerv1.ServiceStatus{
+			Name:               svcConfig.Name,
+			Namespace:          svcConfig.Namespace,
+			Type:               svcConfig.Type,
+			ObservedGeneration: istioRoute.Generation,
+		}
+
+		prev := previousServiceStatus(&istioRoute, svcConfig)
+		if prev != nil {
+			// LastTransitionTime 유지를 위해 기존 Condition 을 이어서 사용
+			svcStatus.Conditions = prev.Conditions
+		}
 
-	for _, svcConfig := range istioRoute.Spec.Services {
-		vs := generator.GenerateVirtualService(svcConfig)
-		if err := ctrl.SetControllerReference(&istioRoute, vs, r.Scheme); err != nil {
+		// 웹훅이 배포되지 않은 환경에서도 잘못된 spec 으로 리소스를 만들지 않도록 같은 규칙으로 검증.
+		// spec 이 바뀌기 전에는 다시 시도해도 결과가 같으므로 재시도하지 않음
+		if errs := desired.ValidateService(i); len(errs) > 0 {
+			return ctrl.Result{}, reconcile.TerminalError(r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonInvalidSpec, errs.ToAggregate()))
+		}
+		// 다크니스 커밋의 Deployment 는 나중에 배포될 수 있으므로 재시도
+		if errs := desired.ValidateServiceSubsets(ctx, r, i); len(errs) > 0 {
+			return ctrl.Result{}, r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonInvalidSpec, errs.ToAggregate())
+		}
+
+		// Steps/Analysis 가 있으면 컨트롤러가 현재 시점의 비율/커밋으로 덮어씀
+		svcConfig, wait, err := r.effectiveService(ctx, svcConfig, prev, &svcStatus, time.Now())
+		if err != nil {
+			return ctrl.Result{}, r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonApplyFailed, err)
+		}
+		requeueAfter = minRequeue(requeueAfter, wait)
+
+		vs, err := generator.GenerateVirtualService(svcConfig)
+		if err != nil {
+			return ctrl.Result{}, r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonInvalidSpec, err)
+		}
+		vsObj := generator.Versioned(vs, version)
+		if err := r.setOwner(vsObj, &istioRoute, "virtual-service"); err != nil {
 			return ctrl.Result{}, err
 		}
 
-		if err := r.CreateOrUpdate(ctx, vs); err != nil {
+		if err := r.Apply(ctx, vsObj); err != nil {
 			logger.Error(err, "failed to manage VirtualService")
-			return ctrl.Result{}, err
+			return ctrl.Result{}, r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonApplyFailed, err)
 		}
+		svcStatus.VirtualService = vs.Name
+		rendered = append(rendered, vsObj)
 
-		ingressVS := generator.GenerateIngressVirtualService(svcConfig)
-		if err := ctrl.SetControllerReference(&istioRoute, ingressVS, r.Scheme); err != nil {
+		ingressVS, err := generator.GenerateIngressVirtualService(svcConfig, generator.GatewayRef(desired))
+		if err != nil {
+			return ctrl.Result{}, r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonInvalidSpec, err)
+		}
+		ingressVSObj := generator.Versioned(ingressVS, version)
+		if err := r.setOwner(ingressVSObj, &istioRoute, "ingress-virtual-service"); err != nil {
 			return ctrl.Result{}, err
 		}
 
-		if err := r.CreateOrUpdate(ctx, ingressVS); err != nil {
+		if err := r.Apply(ctx, ingressVSObj); err != nil {
 			logger.Error(err, "failed to manage VirtualService")
-			return ctrl.Result{}, err
+			return ctrl.Result{}, r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonApplyFailed, err)
 		}
+		svcStatus.IngressVirtualService = ingressVS.Name
+		rendered = append(rendered, ingressVSObj)
 
-		dr := generator.GenerateDestinationRule(svcConfig)
-		if err := ctrl.SetControllerReference(&istioRoute, dr, r.Scheme); err != nil {
+		dr, err := generator.GenerateDestinationRule(svcConfig)
+		if err != nil {
+			return ctrl.Result{}, r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonInvalidSpec, err)
+		}
+		drObj := generator.Versioned(dr, version)
+		if err := r.setOwner(drObj, &istioRoute, "destination-rule"); err != nil {
 			return ctrl.Result{}, err
 		}
-		if err := r.CreateOrUpdate(ctx, dr); err != nil {
+		if err := r.Apply(ctx, drObj); err != nil {
 			logger.Error(err, "failed to manage DestinationRule")
-			return ctrl.Result{}, err
+			return ctrl.Result{}, r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonApplyFailed, err)
 		}
+		svcStatus.DestinationRule = dr.Name
+		rendered = append(rendered, drObj)
 
-		logger.Info(string(svcConfig.Type))
-		fmt.Print(string(svcConfig.Type))
-
-		if svcConfig.Type == meshmanagerv1.CanaryType || svcConfig.Type == meshmanagerv1.StickyCanaryType || svcConfig.Type == meshmanagerv1.StandardType {
+		if generator.NeedsEnvoyFilter(svcConfig) {
 			ef := generator.GenerateEnvoyFilter(svcConfig, &istioRoute)
 
 			logger.Info("Envoy 생성 루틴 시작")
 
-			//if err := ctrl.SetControllerReference(&istioRoute, ef, r.Scheme); err != nil {
-			//	return ctrl.Result{}, err
-			//}
-			if err := r.CreateOrUpdate(ctx, ef); err != nil {
-				logger.Error(err, "failed to manage EnvoyFilter")
+			if err := r.setOwner(ef, &istioRoute, "envoy-filter"); err != nil {
 				return ctrl.Result{}, err
 			}
+			if err := r.Apply(ctx, ef); err != nil {
+				logger.Error(err, "failed to manage EnvoyFilter")
+				return ctrl.Result{}, r.markServiceFailed(ctx, &istioRoute, svcStatuses, svcStatus, meshmanagerv1.ReasonApplyFailed, err)
+			}
+			svcStatus.EnvoyFilter = ef.Name
+			rendered = append(rendered, ef)
 		}
+		// BlueGreen 등으로 타입이 바뀌어 더 이상 생성하지 않는 Lua 필터는 pruneStale 이 소유 여부를 확인한 뒤 정리
 
-	}
-
-	// Finalizer 추가
-	if !controllerutil.ContainsFinalizer(&istioRoute, EnvoyFilterFinalizer) {
-		controllerutil.AddFinalizer(&istioRoute, EnvoyFilterFinalizer)
-		if err := r.Update(ctx, &istioRoute); err != nil {
-			return ctrl.Result{}, err
+		svcStatus.AppliedCommitHashes = append([]string(nil), svcConfig.CommitHashes...)
+		if svcStatus.EffectiveRatio == nil && svcConfig.Type != meshmanagerv1.StandardType && svcConfig.Ratio != nil {
+			ratio := *svcConfig.Ratio
+			svcStatus.EffectiveRatio = &ratio
 		}
-		return ctrl.Result{Requeue: true}, nil
+		if len(svcConfig.Weights) > 0 {
+			svcStatus.EffectiveWeights = svcConfig.CommitWeights()
+		}
+		meta.SetStatusCondition(&svcStatus.Conditions, metav1.Condition{
+			Type:               meshmanagerv1.ConditionReady,
+			Status:             metav1.ConditionTrue,
+			Reason:             meshmanagerv1.ReasonApplied,
+			Message:            "all generated resources are applied",
+			ObservedGeneration: istioRoute.Generation,
+		})
+		svcStatuses = append(svcStatuses, svcStatus)
 	}
 
-	// 삭제 처리
-	if !istioRoute.DeletionTimestamp.IsZero() {
-		if err := r.CleanupEnvoyFilters(ctx, &istioRoute); err != nil {
-			return ctrl.Result{}, err
-		}
-		controllerutil.RemoveFinalizer(&istioRoute, EnvoyFilterFinalizer)
-		return ctrl.Result{}, r.Update(ctx, &istioRoute)
+	// 모든 서비스가 적용된 경우에만 spec 에서 빠진 리소스 정리
+	pruned, err := r.pruneStale(ctx, &istioRoute, rendered, time.Now())
+	if err != nil {
+		logger.Error(err, "failed to prune stale resources")
+		return ctrl.Result{}, r.markFailed(ctx, &istioRoute, svcStatuses, err)
 	}
 
-	//TODO Update Status
+	hash, err := hashRenderedObjects(rendered)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, r.markApplied(ctx, &istioRoute, svcStatuses, pruned, hash)
 }
 
-// CreateOrUpdate to apply k8s resource
-func (r *IstioRouteReconciler) CreateOrUpdate(ctx context.Context, obj client.Object) error {
-	key := client.ObjectKeyFromObject(obj)
-	existing := obj.DeepCopyObject().(client.Object)
+// Apply server-side apply 로 생성한 필드만 관리. 렌더링 결과가 클러스터 상태와 같으면 쓰지 않아
+// 불필요한 resourceVersion 변경과 프록시 push 를 막음. 다른 field manager 가 소유한 필드와 충돌하면
+// 강제로 덮어쓰지 않고 Conflict 에러 반환
+func (r *IstioRouteReconciler) Apply(ctx context.Context, obj client.Object) error {
+	hash, err := hashRenderedObjects([]client.Object{obj})
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[RenderedHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
 
-	if err := r.Get(ctx, key, existing); err != nil {
-		if errors.IsNotFound(err) {
-			return r.Create(ctx, obj)
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(obj), existing); err == nil {
+		upToDate, err := isUpToDate(existing, obj)
+		if err != nil {
+			return err
 		}
+		if upToDate {
+			return nil
+		}
+		if err := r.upgradeManagedFields(ctx, existing); err != nil {
+			return err
+		}
+	} else if !errors.IsNotFound(err) {
 		return err
 	}
 
-	obj.SetResourceVersion(existing.GetResourceVersion())
-	return r.Update(ctx, obj)
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+
+	return r.Patch(ctx, obj, client.Apply, client.FieldOwner(FieldManager))
+}
+
+// legacyFieldManagers server-side apply 이전 버전이 Create/Update 에 사용한 field manager (실행 파일 이름)
+var legacyFieldManagers = sets.New("manager", "main")
+
+// upgradeManagedFields 이전 버전이 Update 로 관리하던 필드를 FieldManager 소유로 옮김.
+// 옮기지 않으면 기존 값을 바꾸는 첫 apply 가 이전 버전 자신과 충돌함. 다른 field manager 는 그대로 둠
+func (r *IstioRouteReconciler) upgradeManagedFields(ctx context.Context, existing client.Object) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(existing, legacyFieldManagers, FieldManager)
+	if err != nil || patch == nil {
+		return err
+	}
+	if err := r.Patch(ctx, existing, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		// 캐시가 오래되어 resourceVersion 이 다른 경우이므로 field manager 충돌로 보고하지 않도록 감싸지 않음
+		return fmt.Errorf("upgrade managed fields of %s/%s: %v", existing.GetNamespace(), existing.GetName(), err)
+	}
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *IstioRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&meshmanagerv1.IstioRoute{}).
-		Owns(&istiov1beta1.VirtualService{}).
-		Owns(&istiov1beta1.DestinationRule{}).
-		Owns(&istiov1beta1.EnvoyFilter{}).
-		Complete(r)
+	// namespace 가 다른 리소스는 ownerReference 가 없으므로 소유 label 로 IstioRoute 를 찾음
+	enqueueOwner := handler.EnqueueRequestsFromMapFunc(mapToOwner)
+
+	// 생성하는 버전과 같은 버전을 감시
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&meshmanagerv1.IstioRoute{})
+	for _, obj := range generator.OwnedTypes(r.networkingVersion()) {
+		b = b.Watches(obj, enqueueOwner)
+	}
+	// 공유 Gateway 는 사용하는 모든 IstioRoute 에 전달
+	b = b.Watches(generator.GatewayType(r.networkingVersion()), handler.EnqueueRequestsFromMapFunc(r.mapGatewayToRoutes))
+	return b.Complete(r)
 }
 
-// CleanupEnvoyFilters to clean up envoy filter resource, For finalizer
-func (r *IstioRouteReconciler) CleanupEnvoyFilters(ctx context.Context, ir *meshmanagerv1.IstioRoute) error {
+// CleanupOwnedResources IstioRoute 삭제 시 이름/namespace/UID 가 모두 일치하는 리소스와,
+// 이 IstioRoute 가 마지막으로 사용하던 Gateway 만 삭제. namespace 가 달라 ownerReference 로 GC 되지 않는
+// EnvoyFilter 등이 대상이며, 실패하면 finalizer 를 유지
+func (r *IstioRouteReconciler) CleanupOwnedResources(ctx context.Context, ir *meshmanagerv1.IstioRoute) error {
 	logger := log.FromContext(ctx)
-	logger.Info("EnvoyFilter 정리 시작", "istioroute", ir.Name)
+	logger.Info("소유 리소스 정리 시작", "istioroute", ir.Name)
 
-	envoyFilterList := &istiov1beta1.EnvoyFilterList{}
-	listOpts := []client.ListOption{
-		client.InNamespace("istio-system"),
-		client.MatchingLabels{
-			"managed-by":      "istioroute-controller",
-			"istioroute-type": "envoy-filter",
-		},
+	owned, err := r.ownedObjects(ctx, ir)
+	if err != nil {
+		return err
+	}
+	var targets []client.Object
+	for _, obj := range owned {
+		if obj.GetAnnotations()[OwnerUIDAnnotation] == string(ir.UID) {
+			targets = append(targets, obj)
+		}
 	}
 
-	if err := r.List(ctx, envoyFilterList, listOpts...); err != nil {
-		logger.Error(err, "EnvoyFilter 리스트 조회 실패")
+	// 삭제 중인 IstioRoute 는 참조에서 제외되므로 다른 IstioRoute 가 사용하는 Gateway 는 남음
+	gateways, err := r.unreferencedGateways(ctx)
+	if err != nil {
 		return err
 	}
+	targets = append(targets, gateways...)
 
-	for i := range envoyFilterList.Items {
-		ef := envoyFilterList.Items[i]
-
-		logger.Info("삭제 대상 EnvoyFilter 정보",
-			"name", ef.Name,
-			"namespace", ef.Namespace,
-			"labels", ef.Labels,
-			"annotations", ef.Annotations,
-		)
-
-		if err := r.Delete(ctx, ef); err != nil {
-			logger.Error(err, "EnvoyFilter 삭제 실패", "name", ef.Name)
+	var errs []error
+	for _, obj := range targets {
+		logger.Info("삭제 대상 리소스", "kind", fmt.Sprintf("%T", obj), "namespace", obj.GetNamespace(), "name", obj.GetName())
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			errs = append(errs, fmt.Errorf("delete %s/%s: %w", obj.GetNamespace(), obj.GetName(), err))
 		}
 	}
-	return nil
+	return utilerrors.NewAggregate(errs)
 }
@@ -21,8 +21,13 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	istionetworkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -46,12 +51,20 @@ var _ = Describe("IstioRoute Controller", func() {
 			By("creating the custom resource for the Kind IstioRoute")
 			err := k8sClient.Get(ctx, typeNamespacedName, istioroute)
 			if err != nil && errors.IsNotFound(err) {
+				ratio := 20
 				resource := &meshmanagerv1.IstioRoute{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: meshmanagerv1.IstioRouteSpec{
+						Services: []meshmanagerv1.ServiceConfig{{
+							Name:         "test-service",
+							Type:         meshmanagerv1.CanaryType,
+							CommitHashes: []string{"v1", "v2"},
+							Ratio:        &ratio,
+						}},
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -65,6 +78,14 @@ var _ = Describe("IstioRoute Controller", func() {
 
 			By("Cleanup the specific resource instance IstioRoute")
 			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			// finalizer 제거
+			controllerReconciler := &IstioRouteReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
@@ -81,4 +102,193 @@ var _ = Describe("IstioRoute Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When one of two IstioRoutes is deleted", func() {
+		ctx := context.Background()
+
+		var reconciler *IstioRouteReconciler
+
+		newRoute := func(name, service string) *meshmanagerv1.IstioRoute {
+			ratio := 20
+			return &meshmanagerv1.IstioRoute{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: meshmanagerv1.IstioRouteSpec{
+					Services: []meshmanagerv1.ServiceConfig{{
+						Name:         service,
+						Type:         meshmanagerv1.CanaryType,
+						CommitHashes: []string{"v1", "v2"},
+						Ratio:        &ratio,
+					}},
+				},
+			}
+		}
+
+		// reconcileRoute finalizer 추가 후 다시 요청하는 경우까지 처리
+		reconcileRoute := func(name string) {
+			for i := 0; i < 3; i++ {
+				result, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: name, Namespace: "default"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				if !result.Requeue {
+					return
+				}
+			}
+		}
+
+		found := func(obj client.Object) bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+			if errors.IsNotFound(err) {
+				return false
+			}
+			Expect(err).NotTo(HaveOccurred())
+			return true
+		}
+
+		BeforeEach(func() {
+			reconciler = &IstioRouteReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			// EnvoyFilter 는 istio-system 에 생성
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-system"}}
+			if err := k8sClient.Create(ctx, ns); err != nil {
+				Expect(errors.IsAlreadyExists(err)).To(BeTrue())
+			}
+
+			By("creating two IstioRoutes that share the default Gateway")
+			Expect(k8sClient.Create(ctx, newRoute("orders-route", "orders"))).To(Succeed())
+			Expect(k8sClient.Create(ctx, newRoute("payments-route", "payments"))).To(Succeed())
+			reconcileRoute("orders-route")
+			reconcileRoute("payments-route")
+		})
+
+		AfterEach(func() {
+			for _, name := range []string{"orders-route", "payments-route"} {
+				route := &meshmanagerv1.IstioRoute{}
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, route)
+				if errors.IsNotFound(err) {
+					continue
+				}
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Delete(ctx, route)).To(Succeed())
+				reconcileRoute(name)
+			}
+		})
+
+		It("should leave the other IstioRoute's resources in place", func() {
+			gateway := &istionetworkingv1alpha3.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "istio-gateway", Namespace: "default"}}
+			ordersFilter := &istionetworkingv1alpha3.EnvoyFilter{ObjectMeta: metav1.ObjectMeta{Name: "orders-filter", Namespace: "istio-system"}}
+			Expect(found(gateway)).To(BeTrue())
+			Expect(found(ordersFilter)).To(BeTrue())
+
+			By("deleting the first IstioRoute")
+			orders := &meshmanagerv1.IstioRoute{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "orders-route", Namespace: "default"}, orders)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, orders)).To(Succeed())
+			reconcileRoute("orders-route")
+			Expect(found(orders)).To(BeFalse())
+
+			By("checking that only the deleted IstioRoute's resources are gone")
+			for _, name := range []string{"orders", "orders-ingress"} {
+				Expect(found(&istionetworkingv1alpha3.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}})).To(BeFalse())
+			}
+			Expect(found(&istionetworkingv1alpha3.DestinationRule{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "default"}})).To(BeFalse())
+			Expect(found(ordersFilter)).To(BeFalse())
+
+			for _, name := range []string{"payments", "payments-ingress"} {
+				Expect(found(&istionetworkingv1alpha3.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}})).To(BeTrue())
+			}
+			Expect(found(&istionetworkingv1alpha3.DestinationRule{ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "default"}})).To(BeTrue())
+			Expect(found(&istionetworkingv1alpha3.EnvoyFilter{ObjectMeta: metav1.ObjectMeta{Name: "payments-filter", Namespace: "istio-system"}})).To(BeTrue())
+			Expect(found(gateway)).To(BeTrue())
+
+			By("deleting the last IstioRoute that uses the Gateway")
+			payments := &meshmanagerv1.IstioRoute{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "payments-route", Namespace: "default"}, payments)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, payments)).To(Succeed())
+			reconcileRoute("payments-route")
+			Expect(found(gateway)).To(BeFalse())
+		})
+	})
+
+	Context("When a darkness release commit has no Deployment", func() {
+		ctx := context.Background()
+
+		key := types.NamespacedName{Name: "search-route", Namespace: "default"}
+		var reconciler *IstioRouteReconciler
+
+		BeforeEach(func() {
+			reconciler = &IstioRouteReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-system"}}
+			if err := k8sClient.Create(ctx, ns); err != nil {
+				Expect(errors.IsAlreadyExists(err)).To(BeTrue())
+			}
+
+			ratio := 20
+			route := &meshmanagerv1.IstioRoute{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec: meshmanagerv1.IstioRouteSpec{
+					Services: []meshmanagerv1.ServiceConfig{{
+						Name:             "search",
+						Type:             meshmanagerv1.CanaryType,
+						CommitHashes:     []string{"v1", "v2"},
+						Ratio:            &ratio,
+						DarknessReleases: []meshmanagerv1.DarknessRelease{{CommitHash: "v2", IPs: []string{"10.0.0.1"}}},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, route)).To(Succeed())
+
+			// finalizer 추가
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			route := &meshmanagerv1.IstioRoute{}
+			Expect(k8sClient.Get(ctx, key, route)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, route)).To(Succeed())
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "search-v2", Namespace: "default"}}
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, deploy))).To(Succeed())
+		})
+
+		It("should retry until the Deployment exists", func() {
+			By("reconciling before the darkness commit is deployed")
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).To(MatchError(ContainSubstring("spec.services[0].darknessReleases[0].commitHash")))
+			Expect(err).NotTo(MatchError(reconcile.TerminalError(nil)))
+
+			route := &meshmanagerv1.IstioRoute{}
+			Expect(k8sClient.Get(ctx, key, route)).To(Succeed())
+			Expect(route.Status.Services).To(HaveLen(1))
+			ready := meta.FindStatusCondition(route.Status.Services[0].Conditions, meshmanagerv1.ConditionReady)
+			Expect(ready.Reason).To(Equal(meshmanagerv1.ReasonInvalidSpec))
+
+			By("deploying the darkness commit")
+			labels := map[string]string{"app": "search", "version": "v2"}
+			deploy := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "search-v2", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "search", Image: "search:v2"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deploy)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
 })
@@ -0,0 +1,313 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+)
+
+// renderedObject 해시 계산 시 사용하는 리소스 표현 (resourceVersion 등 서버 값 제외)
+type renderedObject struct {
+	Kind        string            `json:"kind"`
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Spec        interface{}       `json:"spec"`
+}
+
+// hashRenderedObjects 생성된 리소스들의 spec 기반 sha256 해시
+func hashRenderedObjects(objs []client.Object) (string, error) {
+	var out []renderedObject
+	for _, obj := range objs {
+		spec, err := specOf(obj)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, renderedObject{
+			Kind:        fmt.Sprintf("%T", obj),
+			Namespace:   obj.GetNamespace(),
+			Name:        obj.GetName(),
+			Labels:      obj.GetLabels(),
+			Annotations: obj.GetAnnotations(),
+			Spec:        spec,
+		})
+	}
+
+	jsonData, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("rendered manifest marshal failed: %w", err)
+	}
+	sum := sha256.Sum256(jsonData)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// isUpToDate 마지막 apply 의 해시가 같고 spec 과 소유 label/annotation/ownerReference 가 수동으로 바뀌지 않았으면 true.
+// 다른 도구가 추가한 label 등은 비교하지 않음
+func isUpToDate(existing, desired client.Object) (bool, error) {
+	if existing.GetAnnotations()[RenderedHashAnnotation] != desired.GetAnnotations()[RenderedHashAnnotation] {
+		return false, nil
+	}
+	if !containsAll(existing.GetLabels(), desired.GetLabels()) || !containsAll(existing.GetAnnotations(), desired.GetAnnotations()) {
+		return false, nil
+	}
+	for _, ref := range desired.GetOwnerReferences() {
+		if !slices.ContainsFunc(existing.GetOwnerReferences(), func(live metav1.OwnerReference) bool {
+			return equality.Semantic.DeepEqual(live, ref)
+		}) {
+			return false, nil
+		}
+	}
+
+	live, err := decodedSpec(existing)
+	if err != nil {
+		return false, err
+	}
+	want, err := decodedSpec(desired)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(live, want), nil
+}
+
+// containsAll want 의 모든 key 가 같은 값으로 live 에 있으면 true
+func containsAll(live, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := live[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// decodedSpec protojson 출력은 공백이 일정하지 않으므로 디코딩한 값으로 비교
+func decodedSpec(obj client.Object) (interface{}, error) {
+	spec, err := specOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(spec, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// specOf 오브젝트 전체를 직렬화한 뒤 spec 필드만 추출
+func specOf(obj client.Object) (json.RawMessage, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s/%s failed: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	var fields struct {
+		Spec json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields.Spec, nil
+}
+
+// markApplied 모든 리소스가 적용된 후 상태 기록
+func (r *IstioRouteReconciler) markApplied(ctx context.Context, ir *meshmanagerv1.IstioRoute, services []meshmanagerv1.ServiceStatus,
+	pruned pruneResult, hash string) error {
+	status := ir.Status.DeepCopy()
+	pruned.apply(status)
+
+	progressing := metav1.Condition{
+		Type:    meshmanagerv1.ConditionProgressing,
+		Status:  metav1.ConditionFalse,
+		Reason:  meshmanagerv1.ReasonUpToDate,
+		Message: "rendered manifests are unchanged",
+	}
+	if status.LastAppliedHash != hash {
+		progressing.Status = metav1.ConditionTrue
+		progressing.Reason = meshmanagerv1.ReasonManifestsChanged
+		progressing.Message = "new manifests were applied and are being propagated to proxies"
+	}
+
+	// 자동 롤아웃이 진행 중이면 해시가 같더라도 Progressing 유지
+	for _, svc := range services {
+		if svc.Rollout != nil && svc.Rollout.Phase == meshmanagerv1.RolloutProgressing {
+			progressing.Status = metav1.ConditionTrue
+			progressing.Reason = meshmanagerv1.ReasonRolloutInProgress
+			progressing.Message = fmt.Sprintf("service %s/%s is at rollout step %d (ratio %d%%)",
+				svc.Namespace, svc.Name, svc.Rollout.CurrentStep, derefInt(svc.EffectiveRatio))
+			break
+		}
+	}
+
+	status.LastAppliedHash = hash
+	status.Services = services
+	setConditions(status, ir.Generation,
+		metav1.Condition{
+			Type:    meshmanagerv1.ConditionReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  meshmanagerv1.ReasonApplied,
+			Message: fmt.Sprintf("%d service(s) applied", len(services)),
+		},
+		metav1.Condition{
+			Type:    meshmanagerv1.ConditionDegraded,
+			Status:  metav1.ConditionFalse,
+			Reason:  meshmanagerv1.ReasonApplied,
+			Message: "no errors",
+		},
+		metav1.Condition{
+			Type:    meshmanagerv1.ConditionFieldConflict,
+			Status:  metav1.ConditionFalse,
+			Reason:  meshmanagerv1.ReasonApplied,
+			Message: "all fields are owned by " + FieldManager,
+		},
+		progressing,
+	)
+
+	return r.updateStatus(ctx, ir, status)
+}
+
+// markFailed 적용 실패 시 상태 기록. 원래 에러를 그대로 반환
+func (r *IstioRouteReconciler) markFailed(ctx context.Context, ir *meshmanagerv1.IstioRoute, services []meshmanagerv1.ServiceStatus, cause error) error {
+	status := ir.Status.DeepCopy()
+
+	// 실패 이전까지 처리된 서비스만 갱신하고, 아직 처리하지 못한 서비스는 직전 상태(Rollout 등)를 유지
+	if services != nil {
+		status.Services = mergeServiceStatuses(services, ir.Status.Services)
+	}
+
+	reason := meshmanagerv1.ReasonApplyFailed
+	// 다른 field manager 와 충돌한 경우 덮어쓰지 않았음을 별도 Condition 으로 표시
+	if apierrors.IsConflict(cause) {
+		reason = meshmanagerv1.ReasonFieldManagerConflict
+		setConditions(status, ir.Generation, metav1.Condition{
+			Type:    meshmanagerv1.ConditionFieldConflict,
+			Status:  metav1.ConditionTrue,
+			Reason:  reason,
+			Message: cause.Error(),
+		})
+	}
+
+	setConditions(status, ir.Generation,
+		metav1.Condition{
+			Type:    meshmanagerv1.ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: cause.Error(),
+		},
+		metav1.Condition{
+			Type:    meshmanagerv1.ConditionDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  reason,
+			Message: cause.Error(),
+		},
+		metav1.Condition{
+			Type:    meshmanagerv1.ConditionProgressing,
+			Status:  metav1.ConditionFalse,
+			Reason:  meshmanagerv1.ReasonApplyFailed,
+			Message: "rollout stopped because of an apply error",
+		},
+	)
+
+	if err := r.updateStatus(ctx, ir, status); err != nil {
+		log.FromContext(ctx).Error(err, "failed to update IstioRoute status")
+	}
+	return cause
+}
+
+// markServiceFailed 서비스 리소스를 생성/적용하지 못한 경우 해당 서비스 Condition 에도 원인 기록
+func (r *IstioRouteReconciler) markServiceFailed(ctx context.Context, ir *meshmanagerv1.IstioRoute, services []meshmanagerv1.ServiceStatus,
+	failed meshmanagerv1.ServiceStatus, reason string, cause error) error {
+
+	if apierrors.IsConflict(cause) {
+		reason = meshmanagerv1.ReasonFieldManagerConflict
+	}
+	meta.SetStatusCondition(&failed.Conditions, metav1.Condition{
+		Type:               meshmanagerv1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            cause.Error(),
+		ObservedGeneration: ir.Generation,
+	})
+	services = append(services, failed)
+
+	return r.markFailed(ctx, ir, services, fmt.Errorf("service %s/%s: %w", failed.Namespace, failed.Name, cause))
+}
+
+// mergeServiceStatuses 이번 reconcile 에서 처리한 서비스 뒤에 처리하지 못한 서비스의 직전 상태를 붙임
+func mergeServiceStatuses(processed, previous []meshmanagerv1.ServiceStatus) []meshmanagerv1.ServiceStatus {
+	merged := append([]meshmanagerv1.ServiceStatus(nil), processed...)
+	for _, prev := range previous {
+		found := false
+		for _, svc := range processed {
+			if svc.Name == prev.Name && svc.Namespace == prev.Namespace {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, *prev.DeepCopy())
+		}
+	}
+	return merged
+}
+
+// previousServiceStatus 직전 reconcile 에서 기록한 서비스 상태
+func previousServiceStatus(ir *meshmanagerv1.IstioRoute, svc meshmanagerv1.ServiceConfig) *meshmanagerv1.ServiceStatus {
+	for i := range ir.Status.Services {
+		if ir.Status.Services[i].Name == svc.Name && ir.Status.Services[i].Namespace == svc.Namespace {
+			return ir.Status.Services[i].DeepCopy()
+		}
+	}
+	return nil
+}
+
+func setConditions(status *meshmanagerv1.IstioRouteStatus, generation int64, conds ...metav1.Condition) {
+	status.ObservedGeneration = generation
+	for _, c := range conds {
+		c.ObservedGeneration = generation
+		meta.SetStatusCondition(&status.Conditions, c)
+	}
+}
+
+// updateStatus 상태가 바뀐 경우에만 status subresource 갱신
+func (r *IstioRouteReconciler) updateStatus(ctx context.Context, ir *meshmanagerv1.IstioRoute, status *meshmanagerv1.IstioRouteStatus) error {
+	if equality.Semantic.DeepEqual(&ir.Status, status) {
+		return nil
+	}
+	ir.Status = *status
+	return r.Status().Update(ctx, ir)
+}
+
+func derefInt(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
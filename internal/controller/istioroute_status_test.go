@@ -0,0 +1,177 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiv1beta1 "istio.io/api/networking/v1beta1"
+	istionetworkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+)
+
+// newFakeReconciler Istio 타입을 등록한 fake client 로 API 서버 없이 상태/정리 로직을 확인
+func newFakeReconciler(objs ...client.Object) *IstioRouteReconciler {
+	s := runtime.NewScheme()
+	Expect(meshmanagerv1.AddToScheme(s)).To(Succeed())
+	Expect(istionetworkingv1alpha3.AddToScheme(s)).To(Succeed())
+
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(objs...).
+		WithStatusSubresource(&meshmanagerv1.IstioRoute{}).
+		Build()
+	return &IstioRouteReconciler{Client: c, Scheme: s}
+}
+
+var _ = Describe("isUpToDate", func() {
+	owner := &meshmanagerv1.IstioRoute{ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default", UID: "shop-uid"}}
+
+	desired := func() *istionetworkingv1alpha3.VirtualService {
+		vs := &istionetworkingv1alpha3.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "orders",
+				Namespace:   "default",
+				Annotations: map[string]string{RenderedHashAnnotation: "hash"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: meshmanagerv1.GroupVersion.String(),
+					Kind:       "IstioRoute",
+					Name:       owner.Name,
+					UID:        owner.UID,
+				}},
+			},
+			Spec: apiv1beta1.VirtualService{Hosts: []string{"orders.default.svc.cluster.local"}},
+		}
+		setOwnerLabels(vs, owner, "virtual-service")
+		return vs
+	}
+
+	DescribeTable("skips the apply only when the hash, the live spec and the owner metadata all match",
+		func(mutate func(existing *istionetworkingv1alpha3.VirtualService), expected bool) {
+			existing := desired()
+			existing.ResourceVersion = "42"
+			existing.Labels["added-by"] = "someone-else"
+			mutate(existing)
+
+			upToDate, err := isUpToDate(existing, desired())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upToDate).To(Equal(expected))
+		},
+		Entry("unchanged", func(*istionetworkingv1alpha3.VirtualService) {}, true),
+		Entry("rendered differently", func(vs *istionetworkingv1alpha3.VirtualService) {
+			vs.Annotations[RenderedHashAnnotation] = "previous"
+		}, false),
+		Entry("spec edited by hand", func(vs *istionetworkingv1alpha3.VirtualService) {
+			vs.Spec.Hosts = append(vs.Spec.Hosts, "orders.example.com")
+		}, false),
+		Entry("owner label removed by hand", func(vs *istionetworkingv1alpha3.VirtualService) {
+			delete(vs.Labels, OwnerNameLabel)
+		}, false),
+		Entry("owner label edited by hand", func(vs *istionetworkingv1alpha3.VirtualService) {
+			vs.Labels[ManagedByLabel] = "kubectl"
+		}, false),
+		Entry("owner uid annotation removed by hand", func(vs *istionetworkingv1alpha3.VirtualService) {
+			delete(vs.Annotations, OwnerUIDAnnotation)
+		}, false),
+		Entry("ownerReference removed by hand", func(vs *istionetworkingv1alpha3.VirtualService) {
+			vs.OwnerReferences = nil
+		}, false),
+	)
+})
+
+var _ = Describe("Status conditions", func() {
+	ctx := context.Background()
+
+	var (
+		r  *IstioRouteReconciler
+		ir *meshmanagerv1.IstioRoute
+	)
+
+	BeforeEach(func() {
+		route := &meshmanagerv1.IstioRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "default", Generation: 3},
+		}
+		r = newFakeReconciler(route)
+
+		ir = &meshmanagerv1.IstioRoute{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(route), ir)).To(Succeed())
+		ir.Generation = 3
+	})
+
+	condition := func(conditionType string) *metav1.Condition {
+		return meta.FindStatusCondition(ir.Status.Conditions, conditionType)
+	}
+
+	It("reports Progressing only while new manifests or a rollout are propagating", func() {
+		Expect(r.markApplied(ctx, ir, nil, pruneResult{}, "h1")).To(Succeed())
+		Expect(ir.Status.ObservedGeneration).To(BeEquivalentTo(3))
+		Expect(ir.Status.LastAppliedHash).To(Equal("h1"))
+		Expect(condition(meshmanagerv1.ConditionReady).Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition(meshmanagerv1.ConditionDegraded).Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition(meshmanagerv1.ConditionFieldConflict).Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition(meshmanagerv1.ConditionProgressing).Reason).To(Equal(meshmanagerv1.ReasonManifestsChanged))
+		Expect(condition(meshmanagerv1.ConditionProgressing).ObservedGeneration).To(BeEquivalentTo(3))
+
+		Expect(r.markApplied(ctx, ir, nil, pruneResult{}, "h1")).To(Succeed())
+		Expect(condition(meshmanagerv1.ConditionProgressing).Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition(meshmanagerv1.ConditionProgressing).Reason).To(Equal(meshmanagerv1.ReasonUpToDate))
+
+		ratio := 10
+		services := []meshmanagerv1.ServiceStatus{{
+			Name:           "orders",
+			Namespace:      "default",
+			EffectiveRatio: &ratio,
+			Rollout:        &meshmanagerv1.RolloutStatus{Phase: meshmanagerv1.RolloutProgressing},
+		}}
+		Expect(r.markApplied(ctx, ir, services, pruneResult{}, "h1")).To(Succeed())
+		Expect(condition(meshmanagerv1.ConditionProgressing).Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition(meshmanagerv1.ConditionProgressing).Reason).To(Equal(meshmanagerv1.ReasonRolloutInProgress))
+
+		stored := &meshmanagerv1.IstioRoute{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(ir), stored)).To(Succeed())
+		Expect(stored.Status.Services).To(Equal(services))
+	})
+
+	It("reports a field manager conflict separately from other apply errors", func() {
+		Expect(r.markApplied(ctx, ir, nil, pruneResult{}, "h1")).To(Succeed())
+
+		cause := errors.New("boom")
+		Expect(r.markFailed(ctx, ir, nil, cause)).To(BeIdenticalTo(cause))
+		Expect(condition(meshmanagerv1.ConditionReady).Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition(meshmanagerv1.ConditionReady).Reason).To(Equal(meshmanagerv1.ReasonApplyFailed))
+		Expect(condition(meshmanagerv1.ConditionDegraded).Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition(meshmanagerv1.ConditionFieldConflict).Status).To(Equal(metav1.ConditionFalse))
+		Expect(ir.Status.LastAppliedHash).To(Equal("h1"))
+
+		conflict := apierrors.NewConflict(schema.GroupResource{Group: "networking.istio.io", Resource: "virtualservices"}, "orders", errors.New("owned by kubectl"))
+		Expect(r.markFailed(ctx, ir, nil, conflict)).To(BeIdenticalTo(conflict))
+		Expect(condition(meshmanagerv1.ConditionFieldConflict).Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition(meshmanagerv1.ConditionFieldConflict).Reason).To(Equal(meshmanagerv1.ReasonFieldManagerConflict))
+		Expect(condition(meshmanagerv1.ConditionReady).Reason).To(Equal(meshmanagerv1.ReasonFieldManagerConflict))
+	})
+})
@@ -0,0 +1,307 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	generator "github.com/MeshManager/MeshManagerAgent/internal/controller/generators"
+)
+
+// 생성한 리소스에 붙이는 소유 IstioRoute label
+const (
+	ManagedByLabel      = "managed-by"
+	ManagedByValue      = "istioroute-controller"
+	OwnerNameLabel      = "istioroute-name"
+	OwnerNamespaceLabel = "istioroute-namespace"
+	ResourceTypeLabel   = "istioroute-type"
+
+	// gatewayResourceType 공유 Gateway 의 istioroute-type 값
+	gatewayResourceType = "gateway"
+
+	// OwnerUIDAnnotation 이름이 같은 IstioRoute 를 다시 만든 경우와 구분하기 위한 UID
+	OwnerUIDAnnotation = "istioroute-controller/owner-uid"
+
+	// FieldManager server-side apply 에 사용하는 field manager
+	FieldManager = "istioroute-controller"
+
+	// RenderedHashAnnotation 마지막으로 apply 한 렌더링 결과의 해시. 같으면 다시 쓰지 않음
+	RenderedHashAnnotation = "istioroute-controller/rendered-hash"
+
+	// PruneHoldAnnotation 값이 "true" 이면 spec 에서 빠져도 삭제하지 않음 (수동 보류)
+	PruneHoldAnnotation = "istioroute-controller/hold"
+
+	// status 에 남길 정리 이력 최대 개수
+	maxPrunedResources = 20
+)
+
+// setOwnerLabels 리소스를 만든 IstioRoute 를 label 로 기록. namespace 가 달라도 조회 가능
+func setOwnerLabels(obj client.Object, ir *meshmanagerv1.IstioRoute, resourceType string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[ManagedByLabel] = ManagedByValue
+	labels[OwnerNameLabel] = ir.Name
+	labels[OwnerNamespaceLabel] = ir.Namespace
+	labels[ResourceTypeLabel] = resourceType
+	obj.SetLabels(labels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[OwnerUIDAnnotation] = string(ir.UID)
+	obj.SetAnnotations(annotations)
+}
+
+// setOwner 소유 label 을 붙이고, 같은 namespace 인 경우에만 ownerReference 설정.
+// namespace 가 다른 리소스는 label 기반 watch 와 finalizer 정리로 관리
+func (r *IstioRouteReconciler) setOwner(obj client.Object, ir *meshmanagerv1.IstioRoute, resourceType string) error {
+	setOwnerLabels(obj, ir, resourceType)
+	if obj.GetNamespace() != ir.Namespace {
+		return nil
+	}
+	return controllerutil.SetControllerReference(ir, obj, r.Scheme)
+}
+
+// setSharedLabels Gateway 는 여러 IstioRoute 가 같은 이름으로 공유할 수 있으므로 특정 IstioRoute 를
+// 소유자로 기록하지 않음. ownerReference 가 있으면 그 IstioRoute 삭제 시 다른 IstioRoute 의 게이트웨이까지 GC 됨
+func setSharedLabels(obj client.Object, resourceType string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[ManagedByLabel] = ManagedByValue
+	labels[ResourceTypeLabel] = resourceType
+	obj.SetLabels(labels)
+}
+
+// gatewayReferences 삭제 중이 아닌 IstioRoute 를 사용하는 Gateway(<namespace>/<name>)별로 묶음.
+// 기본값을 적용한 복사본이므로 spec.gateway 를 생략한 IstioRoute 도 포함
+func (r *IstioRouteReconciler) gatewayReferences(ctx context.Context) (map[string][]*meshmanagerv1.IstioRoute, error) {
+	var routes meshmanagerv1.IstioRouteList
+	if err := r.List(ctx, &routes); err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string][]*meshmanagerv1.IstioRoute)
+	for i := range routes.Items {
+		ir := routes.Items[i].DeepCopy()
+		if !ir.DeletionTimestamp.IsZero() {
+			continue
+		}
+		ir.SetDefaults()
+		ref := generator.GatewayRef(ir)
+		refs[ref] = append(refs[ref], ir)
+	}
+	return refs, nil
+}
+
+// checkSharedGateway 같은 Gateway 를 사용하는 다른 IstioRoute 와 게이트웨이 설정이 다르면 에러.
+// 서로 다른 설정을 번갈아 적용하며 덮어쓰지 않도록 설정이 일치할 때까지 적용하지 않음
+func (r *IstioRouteReconciler) checkSharedGateway(ctx context.Context, ir *meshmanagerv1.IstioRoute) error {
+	refs, err := r.gatewayReferences(ctx)
+	if err != nil {
+		return err
+	}
+
+	ref := generator.GatewayRef(ir)
+	want := generator.GenerateIstioGateway(ir)
+	for _, other := range refs[ref] {
+		if other.Namespace == ir.Namespace && other.Name == ir.Name {
+			continue
+		}
+		if !proto.Equal(&want.Spec, &generator.GenerateIstioGateway(other).Spec) {
+			return fmt.Errorf("gateway %s is shared with IstioRoute %s/%s but its settings differ", ref, other.Namespace, other.Name)
+		}
+	}
+	return nil
+}
+
+// mapGatewayToRoutes Gateway 의 변경/삭제 이벤트를 그 Gateway 를 사용하는 모든 IstioRoute 의 reconcile 요청으로 변환
+func (r *IstioRouteReconciler) mapGatewayToRoutes(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetLabels()[ManagedByLabel] != ManagedByValue {
+		return nil
+	}
+
+	refs, err := r.gatewayReferences(ctx)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list IstioRoutes for Gateway event", "gateway", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ir := range refs[fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())] {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ir)})
+	}
+	return requests
+}
+
+// mapToOwner 생성한 리소스의 변경/삭제 이벤트를 소유 IstioRoute 의 reconcile 요청으로 변환
+func mapToOwner(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	if labels[ManagedByLabel] != ManagedByValue || labels[OwnerNameLabel] == "" {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Name: labels[OwnerNameLabel], Namespace: labels[OwnerNamespaceLabel]},
+	}}
+}
+
+func ownedBy(ir *meshmanagerv1.IstioRoute) client.MatchingLabels {
+	return client.MatchingLabels{
+		ManagedByLabel:      ManagedByValue,
+		OwnerNameLabel:      ir.Name,
+		OwnerNamespaceLabel: ir.Namespace,
+	}
+}
+
+// resourceRef 버전과 관계없이 Kind/namespace/name 으로 비교
+func (r *IstioRouteReconciler) resourceRef(obj client.Object) (meshmanagerv1.ResourceRef, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return meshmanagerv1.ResourceRef{}, err
+	}
+	return meshmanagerv1.ResourceRef{Kind: gvk.Kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}, nil
+}
+
+type pruneResult struct {
+	pruned []meshmanagerv1.PrunedResource
+	held   []meshmanagerv1.ResourceRef
+}
+
+// ownedObjects IstioRoute 가 소유 label 로 기록된 리소스
+func (r *IstioRouteReconciler) ownedObjects(ctx context.Context, ir *meshmanagerv1.IstioRoute) ([]client.Object, error) {
+	var out []client.Object
+	for _, list := range generator.OwnedListTypes(r.networkingVersion()) {
+		if err := r.List(ctx, list, ownedBy(ir)); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if obj, ok := item.(client.Object); ok {
+				out = append(out, obj)
+			}
+		}
+	}
+	return out, nil
+}
+
+// unreferencedGateways 생성한 Gateway 중 삭제 중이 아닌 어떤 IstioRoute 도 사용하지 않는 것.
+// spec.gateway 를 바꾸거나 마지막 IstioRoute 가 삭제되어 남은 Gateway 가 대상
+func (r *IstioRouteReconciler) unreferencedGateways(ctx context.Context) ([]client.Object, error) {
+	refs, err := r.gatewayReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := generator.GatewayListType(r.networkingVersion())
+	if err := r.List(ctx, list, client.MatchingLabels{ManagedByLabel: ManagedByValue, ResourceTypeLabel: gatewayResourceType}); err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []client.Object
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok || len(refs[fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())]) > 0 {
+			continue
+		}
+		out = append(out, obj)
+	}
+	return out, nil
+}
+
+// pruneStale 이번 reconcile 에서 생성하지 않은 소유 리소스와 더 이상 사용하지 않는 Gateway 삭제.
+// hold 어노테이션이 있으면 보류
+func (r *IstioRouteReconciler) pruneStale(ctx context.Context, ir *meshmanagerv1.IstioRoute, rendered []client.Object, now time.Time) (pruneResult, error) {
+	logger := log.FromContext(ctx)
+	var result pruneResult
+
+	desired := make(map[meshmanagerv1.ResourceRef]struct{}, len(rendered))
+	for _, obj := range rendered {
+		ref, err := r.resourceRef(obj)
+		if err != nil {
+			return result, err
+		}
+		desired[ref] = struct{}{}
+	}
+
+	candidates, err := r.ownedObjects(ctx, ir)
+	if err != nil {
+		return result, err
+	}
+	gateways, err := r.unreferencedGateways(ctx)
+	if err != nil {
+		return result, err
+	}
+	candidates = append(candidates, gateways...)
+
+	for _, obj := range candidates {
+		ref, err := r.resourceRef(obj)
+		if err != nil {
+			return result, err
+		}
+		if _, ok := desired[ref]; ok {
+			continue
+		}
+		if obj.GetAnnotations()[PruneHoldAnnotation] == "true" {
+			result.held = append(result.held, ref)
+			continue
+		}
+
+		logger.Info("spec 에서 빠진 리소스 삭제", "kind", ref.Kind, "namespace", ref.Namespace, "name", ref.Name)
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return result, fmt.Errorf("prune %s %s/%s: %w", ref.Kind, ref.Namespace, ref.Name, err)
+		}
+		result.pruned = append(result.pruned, meshmanagerv1.PrunedResource{ResourceRef: ref, PrunedAt: metav1.NewTime(now)})
+	}
+
+	return result, nil
+}
+
+// apply 이번에 정리한 리소스를 이력 앞에 추가하고 보류 목록 갱신
+func (p pruneResult) apply(status *meshmanagerv1.IstioRouteStatus) {
+	if len(p.pruned) > 0 {
+		history := append(append([]meshmanagerv1.PrunedResource(nil), p.pruned...), status.PrunedResources...)
+		if len(history) > maxPrunedResources {
+			history = history[:maxPrunedResources]
+		}
+		status.PrunedResources = history
+	}
+	status.HeldResources = p.held
+}
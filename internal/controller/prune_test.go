@@ -0,0 +1,192 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	istionetworkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+)
+
+func newTestRoute(name, uid, gateway string) *meshmanagerv1.IstioRoute {
+	return &meshmanagerv1.IstioRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(uid)},
+		Spec: meshmanagerv1.IstioRouteSpec{
+			Gateway: &meshmanagerv1.GatewayConfig{Name: gateway},
+		},
+	}
+}
+
+func ownedEnvoyFilter(name string, ir *meshmanagerv1.IstioRoute) *istionetworkingv1alpha3.EnvoyFilter {
+	ef := &istionetworkingv1alpha3.EnvoyFilter{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system"},
+	}
+	setOwnerLabels(ef, ir, "envoy-filter")
+	return ef
+}
+
+func sharedGateway(name string) *istionetworkingv1alpha3.Gateway {
+	gw := &istionetworkingv1alpha3.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+	}
+	setSharedLabels(gw, gatewayResourceType)
+	return gw
+}
+
+// exists NotFound 이면 false, 그 외 에러는 실패
+func exists(ctx context.Context, c client.Client, obj client.Object) bool {
+	err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if apierrors.IsNotFound(err) {
+		return false
+	}
+	Expect(err).NotTo(HaveOccurred())
+	return true
+}
+
+var _ = Describe("Owned resource cleanup", func() {
+	ctx := context.Background()
+
+	It("deletes only the deleted IstioRoute's resources and the Gateways nobody else uses", func() {
+		deleted := newTestRoute("orders", "uid-orders", "shared")
+		deleted.Finalizers = []string{EnvoyFilterFinalizer}
+		deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		// 같은 Gateway 를 쓰는 다른 IstioRoute 와, 삭제되는 IstioRoute 만 쓰던 Gateway
+		other := newTestRoute("payments", "uid-payments", "shared")
+		onlyDeleted := newTestRoute("orders-admin", "uid-orders-admin", "admin")
+		onlyDeleted.Finalizers = []string{EnvoyFilterFinalizer}
+		onlyDeleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		ordersFilter := ownedEnvoyFilter("orders-filter", deleted)
+		paymentsFilter := ownedEnvoyFilter("payments-filter", other)
+		shared := sharedGateway("shared")
+		admin := sharedGateway("admin")
+
+		r := newFakeReconciler(deleted, other, onlyDeleted, ordersFilter, paymentsFilter, shared, admin)
+		Expect(r.CleanupOwnedResources(ctx, deleted)).To(Succeed())
+
+		Expect(exists(ctx, r.Client, ordersFilter)).To(BeFalse())
+		Expect(exists(ctx, r.Client, paymentsFilter)).To(BeTrue())
+		Expect(exists(ctx, r.Client, shared)).To(BeTrue())
+		Expect(exists(ctx, r.Client, admin)).To(BeFalse())
+	})
+
+	It("keeps resources recorded for a previous IstioRoute with the same name", func() {
+		current := newTestRoute("orders", "uid-new", "shared")
+		previous := ownedEnvoyFilter("orders-filter", newTestRoute("orders", "uid-old", "shared"))
+
+		r := newFakeReconciler(current, previous)
+		Expect(r.CleanupOwnedResources(ctx, current)).To(Succeed())
+		Expect(exists(ctx, r.Client, previous)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Pruning", func() {
+	ctx := context.Background()
+
+	It("deletes owned resources missing from the rendered set unless they are held", func() {
+		ir := newTestRoute("orders", "uid-orders", "shared")
+		kept := ownedEnvoyFilter("orders-filter", ir)
+		stale := ownedEnvoyFilter("orders-old-filter", ir)
+		held := ownedEnvoyFilter("orders-held-filter", ir)
+		held.Annotations[PruneHoldAnnotation] = "true"
+		otherRoute := ownedEnvoyFilter("payments-filter", newTestRoute("payments", "uid-payments", "shared"))
+		shared := sharedGateway("shared")
+		unused := sharedGateway("unused")
+
+		r := newFakeReconciler(ir, kept, stale, held, otherRoute, shared, unused)
+		now := time.Now()
+		rendered := []client.Object{ownedEnvoyFilter("orders-filter", ir), sharedGateway("shared")}
+
+		result, err := r.pruneStale(ctx, ir, rendered, now)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result.pruned).To(ConsistOf(
+			meshmanagerv1.PrunedResource{
+				ResourceRef: meshmanagerv1.ResourceRef{Kind: "EnvoyFilter", Namespace: "istio-system", Name: "orders-old-filter"},
+				PrunedAt:    metav1.NewTime(now),
+			},
+			meshmanagerv1.PrunedResource{
+				ResourceRef: meshmanagerv1.ResourceRef{Kind: "Gateway", Namespace: "default", Name: "unused"},
+				PrunedAt:    metav1.NewTime(now),
+			},
+		))
+		Expect(result.held).To(ConsistOf(
+			meshmanagerv1.ResourceRef{Kind: "EnvoyFilter", Namespace: "istio-system", Name: "orders-held-filter"},
+		))
+
+		Expect(exists(ctx, r.Client, kept)).To(BeTrue())
+		Expect(exists(ctx, r.Client, stale)).To(BeFalse())
+		Expect(exists(ctx, r.Client, held)).To(BeTrue())
+		Expect(exists(ctx, r.Client, otherRoute)).To(BeTrue())
+		Expect(exists(ctx, r.Client, shared)).To(BeTrue())
+		Expect(exists(ctx, r.Client, unused)).To(BeFalse())
+	})
+
+	It("prepends pruned resources to a bounded history and replaces the held list", func() {
+		status := &meshmanagerv1.IstioRouteStatus{
+			HeldResources: []meshmanagerv1.ResourceRef{{Kind: "EnvoyFilter", Namespace: "istio-system", Name: "released"}},
+		}
+		for i := 0; i < maxPrunedResources; i++ {
+			status.PrunedResources = append(status.PrunedResources, meshmanagerv1.PrunedResource{
+				ResourceRef: meshmanagerv1.ResourceRef{Kind: "VirtualService", Namespace: "default", Name: fmt.Sprintf("old-%d", i)},
+			})
+		}
+
+		newest := meshmanagerv1.PrunedResource{ResourceRef: meshmanagerv1.ResourceRef{Kind: "DestinationRule", Namespace: "default", Name: "new"}}
+		held := meshmanagerv1.ResourceRef{Kind: "EnvoyFilter", Namespace: "istio-system", Name: "held"}
+		pruneResult{pruned: []meshmanagerv1.PrunedResource{newest}, held: []meshmanagerv1.ResourceRef{held}}.apply(status)
+
+		Expect(status.PrunedResources).To(HaveLen(maxPrunedResources))
+		Expect(status.PrunedResources[0]).To(Equal(newest))
+		Expect(status.PrunedResources[1].Name).To(Equal("old-0"))
+		Expect(status.HeldResources).To(Equal([]meshmanagerv1.ResourceRef{held}))
+
+		pruneResult{}.apply(status)
+		Expect(status.PrunedResources).To(HaveLen(maxPrunedResources))
+		Expect(status.HeldResources).To(BeEmpty())
+	})
+})
+
+var _ = Describe("mapToOwner", func() {
+	DescribeTable("maps generated resources to the IstioRoute recorded in their labels",
+		func(obj client.Object, expected []reconcile.Request) {
+			Expect(mapToOwner(context.Background(), obj)).To(Equal(expected))
+		},
+		Entry("owned resource in another namespace",
+			ownedEnvoyFilter("orders-filter", newTestRoute("orders", "uid-orders", "shared")),
+			[]reconcile.Request{{NamespacedName: types.NamespacedName{Name: "orders", Namespace: "default"}}}),
+		Entry("shared Gateway has no single owner", sharedGateway("shared"), nil),
+		Entry("resource created by someone else", &istionetworkingv1alpha3.EnvoyFilter{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "orders-filter",
+				Namespace: "istio-system",
+				Labels:    map[string]string{OwnerNameLabel: "orders", OwnerNamespaceLabel: "default"},
+			},
+		}, nil),
+	)
+})
@@ -0,0 +1,217 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+	"github.com/MeshManager/MeshManagerAgent/external/env_service"
+	"github.com/MeshManager/MeshManagerAgent/internal/controller/analysis"
+)
+
+// effectiveService Steps 와 Analysis 상태를 반영해 실제로 생성에 사용할 ServiceConfig 를 계산하고
+// svcStatus 에 진행 상태를 기록. 반환하는 Duration 은 다음 평가까지 남은 시간
+func (r *IstioRouteReconciler) effectiveService(ctx context.Context, svc meshmanagerv1.ServiceConfig, prev *meshmanagerv1.ServiceStatus,
+	svcStatus *meshmanagerv1.ServiceStatus, now time.Time) (meshmanagerv1.ServiceConfig, time.Duration, error) {
+
+	var prevRollout *meshmanagerv1.RolloutStatus
+	var prevAnalysis *meshmanagerv1.AnalysisStatus
+	if prev != nil {
+		prevRollout = prev.Rollout
+		prevAnalysis = prev.Analysis
+	}
+
+	var requeueAfter time.Duration
+
+	// Steps 가 있으면 현재 단계의 비율로 Ratio 를 덮어씀
+	rollout, stepRatio, stepRemaining, err := advanceRollout(svc, prevRollout, now)
+	if err != nil {
+		return svc, 0, err
+	}
+	if rollout != nil {
+		svc.Ratio = &stepRatio
+		svcStatus.Rollout = rollout
+		requeueAfter = minRequeue(requeueAfter, stepRemaining)
+	}
+
+	// 단계가 모두 끝난 경우 분석 생략
+	if svc.Analysis == nil || !isCanary(svc) || len(svc.CommitHashes) != 2 ||
+		(rollout != nil && rollout.Phase == meshmanagerv1.RolloutPromoted) {
+		return svc, requeueAfter, nil
+	}
+
+	status, wait, err := r.analyze(ctx, svc, prevAnalysis, rollout == nil, now)
+	if err != nil {
+		return svc, 0, err
+	}
+	svcStatus.Analysis = status
+	requeueAfter = minRequeue(requeueAfter, wait)
+
+	switch status.Phase {
+	case meshmanagerv1.AnalysisAborted:
+		// 롤백 후에는 단계 진행도 중단
+		svc = pinToCommit(svc, svc.CommitHashes[0])
+		ratio := 0
+		svcStatus.EffectiveRatio = &ratio
+		svcStatus.Rollout = nil
+		requeueAfter = 0
+	case meshmanagerv1.AnalysisPromoted:
+		svc = pinToCommit(svc, svc.CommitHashes[1])
+		ratio := 100
+		svcStatus.EffectiveRatio = &ratio
+	}
+
+	return svc, requeueAfter, nil
+}
+
+// analyze Interval 이 지났을 때만 메트릭을 조회해 분석 상태 갱신
+func (r *IstioRouteReconciler) analyze(ctx context.Context, svc meshmanagerv1.ServiceConfig, prev *meshmanagerv1.AnalysisStatus,
+	promoteOnSuccess bool, now time.Time) (*meshmanagerv1.AnalysisStatus, time.Duration, error) {
+
+	interval, err := time.ParseDuration(svc.Analysis.Interval)
+	if err != nil {
+		return nil, 0, fmt.Errorf("analysis.interval %q: %w", svc.Analysis.Interval, err)
+	}
+
+	canary := svc.CommitHashes[1]
+	if prev != nil && prev.CommitHash == canary {
+		if prev.Phase != meshmanagerv1.AnalysisRunning {
+			return prev.DeepCopy(), 0, nil
+		}
+		if prev.LastCheckedAt != nil {
+			if remaining := prev.LastCheckedAt.Add(interval).Sub(now); remaining > 0 {
+				return prev.DeepCopy(), remaining, nil
+			}
+		}
+	}
+
+	analyzer := r.Analyzer
+	if analyzer == nil {
+		address, _ := env_service.GetPrometheusUrl()
+		analyzer = analysis.New(address)
+	}
+
+	results, noData, evalErr := analyzer.Evaluate(ctx, svc, canary)
+	if evalErr != nil {
+		log.FromContext(ctx).Error(evalErr, "canary analysis failed", "service", svc.Name, "commitHash", canary)
+	}
+
+	status := analysis.Next(prev, svc.Analysis, canary, results, noData, evalErr, promoteOnSuccess, now)
+	if status.Phase != meshmanagerv1.AnalysisRunning {
+		return status, 0, nil
+	}
+	return status, interval, nil
+}
+
+// pinToCommit 분석 결과가 확정된 서비스를 단일 커밋으로 고정
+func pinToCommit(svc meshmanagerv1.ServiceConfig, hash string) meshmanagerv1.ServiceConfig {
+	svc.Type = meshmanagerv1.StandardType
+	svc.CommitHashes = []string{hash}
+	svc.Ratio = nil
+	svc.Weights = nil
+	svc.Steps = nil
+	return svc
+}
+
+// advanceRollout Steps 에 따라 현재 적용할 비율을 계산하고 다음 단계까지 남은 시간을 반환.
+// Steps 가 없거나 Canary 계열이 아니면 nil 을 반환하고 Spec 의 Ratio 를 그대로 사용
+func advanceRollout(svc meshmanagerv1.ServiceConfig, prev *meshmanagerv1.RolloutStatus, now time.Time) (*meshmanagerv1.RolloutStatus, int, time.Duration, error) {
+	if len(svc.Steps) == 0 || !isCanary(svc) {
+		return nil, 0, 0, nil
+	}
+
+	durations := make([]time.Duration, len(svc.Steps))
+	for i, step := range svc.Steps {
+		d, err := time.ParseDuration(step.Duration)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("steps[%d].duration %q: %w", i, step.Duration, err)
+		}
+		durations[i] = d
+	}
+
+	revision, err := rolloutRevision(svc)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	// 최초 실행 또는 커밋/단계 변경 시 첫 단계부터 시작
+	status := &meshmanagerv1.RolloutStatus{
+		Phase:         meshmanagerv1.RolloutProgressing,
+		CurrentStep:   0,
+		StepStartedAt: metav1.NewTime(now),
+		Revision:      revision,
+	}
+	if prev != nil && prev.Revision == revision {
+		status = prev.DeepCopy()
+	}
+
+	for status.Phase == meshmanagerv1.RolloutProgressing {
+		stepEnd := status.StepStartedAt.Add(durations[status.CurrentStep])
+		if now.Before(stepEnd) {
+			break
+		}
+		status.CurrentStep++
+		status.StepStartedAt = metav1.NewTime(stepEnd)
+		if status.CurrentStep >= len(svc.Steps) {
+			status.Phase = meshmanagerv1.RolloutPromoted
+		}
+	}
+
+	if status.Phase == meshmanagerv1.RolloutPromoted {
+		return status, 100, 0, nil
+	}
+
+	requeueAfter := status.StepStartedAt.Add(durations[status.CurrentStep]).Sub(now)
+	return status, svc.Steps[status.CurrentStep].Weight, requeueAfter, nil
+}
+
+// rolloutRevision 커밋 해시와 단계 정의로 만든 식별자
+func rolloutRevision(svc meshmanagerv1.ServiceConfig) (string, error) {
+	data, err := json.Marshal(struct {
+		CommitHashes []string                   `json:"commitHashes"`
+		Steps        []meshmanagerv1.CanaryStep `json:"steps"`
+	}{svc.CommitHashes, svc.Steps})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+func isCanary(svc meshmanagerv1.ServiceConfig) bool {
+	return svc.Type == meshmanagerv1.CanaryType || svc.Type == meshmanagerv1.StickyCanaryType
+}
+
+// minRequeue 0 이 아닌 값 중 가장 짧은 대기 시간
+func minRequeue(current, next time.Duration) time.Duration {
+	if next <= 0 {
+		return current
+	}
+	if current <= 0 || next < current {
+		return next
+	}
+	return current
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+)
+
+var _ = Describe("advanceRollout", func() {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rolloutService := func() meshmanagerv1.ServiceConfig {
+		return meshmanagerv1.ServiceConfig{
+			Name:         "orders",
+			Type:         meshmanagerv1.CanaryType,
+			CommitHashes: []string{"v1", "v2"},
+			Steps: []meshmanagerv1.CanaryStep{
+				{Weight: 10, Duration: "10m"},
+				{Weight: 50, Duration: "20m"},
+			},
+		}
+	}
+
+	// started 시점에 첫 단계를 시작한 상태
+	startedAt := func(svc meshmanagerv1.ServiceConfig) *meshmanagerv1.RolloutStatus {
+		status, _, _, err := advanceRollout(svc, nil, start)
+		Expect(err).NotTo(HaveOccurred())
+		return status
+	}
+
+	DescribeTable("moves through the steps as their durations elapse",
+		func(elapsed time.Duration, phase meshmanagerv1.RolloutPhase, step, ratio int, requeue time.Duration) {
+			svc := rolloutService()
+
+			status, gotRatio, gotRequeue, err := advanceRollout(svc, startedAt(svc), start.Add(elapsed))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Phase).To(Equal(phase))
+			Expect(status.CurrentStep).To(Equal(step))
+			Expect(gotRatio).To(Equal(ratio))
+			Expect(gotRequeue).To(Equal(requeue))
+		},
+		Entry("first step", 5*time.Minute, meshmanagerv1.RolloutProgressing, 0, 10, 5*time.Minute),
+		Entry("second step starts exactly when the first ends", 10*time.Minute, meshmanagerv1.RolloutProgressing, 1, 50, 20*time.Minute),
+		Entry("second step", 25*time.Minute, meshmanagerv1.RolloutProgressing, 1, 50, 5*time.Minute),
+		Entry("promoted after the last step", 30*time.Minute, meshmanagerv1.RolloutPromoted, 2, 100, time.Duration(0)),
+		Entry("promoted even if several steps were missed", 3*time.Hour, meshmanagerv1.RolloutPromoted, 2, 100, time.Duration(0)),
+	)
+
+	It("keeps the step start time of the step it advanced into", func() {
+		svc := rolloutService()
+
+		status, _, _, err := advanceRollout(svc, startedAt(svc), start.Add(15*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.StepStartedAt).To(Equal(metav1.NewTime(start.Add(10 * time.Minute))))
+	})
+
+	It("restarts from the first step when the commits change", func() {
+		svc := rolloutService()
+		prev := startedAt(svc)
+
+		svc.CommitHashes = []string{"v1", "v3"}
+		now := start.Add(25 * time.Minute)
+		status, ratio, requeue, err := advanceRollout(svc, prev, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Revision).NotTo(Equal(prev.Revision))
+		Expect(status.CurrentStep).To(Equal(0))
+		Expect(status.StepStartedAt).To(Equal(metav1.NewTime(now)))
+		Expect(ratio).To(Equal(10))
+		Expect(requeue).To(Equal(10 * time.Minute))
+	})
+
+	DescribeTable("leaves the ratio to the spec",
+		func(mutate func(*meshmanagerv1.ServiceConfig)) {
+			svc := rolloutService()
+			mutate(&svc)
+
+			status, _, requeue, err := advanceRollout(svc, nil, start)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(BeNil())
+			Expect(requeue).To(BeZero())
+		},
+		Entry("without steps", func(svc *meshmanagerv1.ServiceConfig) { svc.Steps = nil }),
+		Entry("for non canary types", func(svc *meshmanagerv1.ServiceConfig) { svc.Type = meshmanagerv1.StandardType }),
+	)
+
+	It("rejects an invalid step duration", func() {
+		svc := rolloutService()
+		svc.Steps[1].Duration = "soon"
+
+		_, _, _, err := advanceRollout(svc, nil, start)
+		Expect(err).To(MatchError(ContainSubstring("steps[1].duration")))
+	})
+})
+
+var _ = Describe("Rollout status on apply failures", func() {
+	ctx := context.Background()
+
+	It("keeps the rollout of services after the one that failed to apply", func() {
+		steps := []meshmanagerv1.CanaryStep{{Weight: 10, Duration: "10m"}, {Weight: 50, Duration: "20m"}}
+		service := func(name string) meshmanagerv1.ServiceConfig {
+			return meshmanagerv1.ServiceConfig{
+				Name:         name,
+				Namespace:    "default",
+				Type:         meshmanagerv1.CanaryType,
+				CommitHashes: []string{"v1", "v2"},
+				Steps:        steps,
+			}
+		}
+		shipping := meshmanagerv1.ServiceStatus{
+			Name:      "shipping",
+			Namespace: "default",
+			Type:      meshmanagerv1.CanaryType,
+			Rollout: &meshmanagerv1.RolloutStatus{
+				Phase:         meshmanagerv1.RolloutProgressing,
+				CurrentStep:   1,
+				StepStartedAt: metav1.NewTime(time.Now().Add(-5 * time.Minute).Truncate(time.Second)),
+				Revision:      "previous",
+			},
+		}
+		route := &meshmanagerv1.IstioRoute{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "shop",
+				Namespace:  "default",
+				UID:        types.UID("shop-uid"),
+				Finalizers: []string{EnvoyFilterFinalizer},
+			},
+			Spec: meshmanagerv1.IstioRouteSpec{
+				Services: []meshmanagerv1.ServiceConfig{service("orders"), service("payments"), service("shipping")},
+			},
+			Status: meshmanagerv1.IstioRouteStatus{Services: []meshmanagerv1.ServiceStatus{shipping}},
+		}
+		r := newFakeReconciler(route)
+
+		// fake client 는 server-side apply 를 처리하지 않으므로 apply 는 성공한 것으로 보고 두 번째 서비스만 실패시킴
+		r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return c.Patch(ctx, obj, patch, opts...)
+				}
+				if obj.GetName() == "payments" {
+					return errors.New("apply failed")
+				}
+				return nil
+			},
+		})
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(route)})
+		Expect(err).To(MatchError(ContainSubstring("apply failed")))
+
+		stored := &meshmanagerv1.IstioRoute{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(route), stored)).To(Succeed())
+		Expect(stored.Status.Services).To(HaveLen(3))
+		Expect(stored.Status.Services[0].Name).To(Equal("orders"))
+		Expect(stored.Status.Services[0].Rollout).NotTo(BeNil())
+		Expect(stored.Status.Services[1].Name).To(Equal("payments"))
+		Expect(stored.Status.Services[1].Rollout).NotTo(BeNil())
+		Expect(meta.IsStatusConditionFalse(stored.Status.Services[1].Conditions, meshmanagerv1.ConditionReady)).To(BeTrue())
+		Expect(stored.Status.Services[2]).To(Equal(shipping))
+	})
+})
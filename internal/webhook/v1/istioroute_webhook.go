@@ -0,0 +1,129 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var istioroutelog = logf.Log.WithName("istioroute-resource")
+
+// SetupIstioRouteWebhookWithManager registers the webhook for IstioRoute in the manager.
+func SetupIstioRouteWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&meshmanagerv1.IstioRoute{}).
+		WithValidator(&IstioRouteCustomValidator{Client: mgr.GetAPIReader()}).
+		WithDefaulter(&IstioRouteCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-mesh-manager-meshmanager-com-v1-istioroute,mutating=true,failurePolicy=fail,sideEffects=None,groups=mesh-manager.meshmanager.com,resources=istioroutes,verbs=create;update,versions=v1,name=mistioroute-v1.kb.io,admissionReviewVersions=v1
+
+// IstioRouteCustomDefaulter struct is responsible for setting default values on the custom resource of the
+// Kind IstioRoute when those are created or updated.
+type IstioRouteCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &IstioRouteCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind IstioRoute.
+func (d *IstioRouteCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	istioroute, ok := obj.(*meshmanagerv1.IstioRoute)
+	if !ok {
+		return fmt.Errorf("expected an IstioRoute object but got %T", obj)
+	}
+	istioroutelog.Info("Defaulting for IstioRoute", "name", istioroute.GetName())
+
+	// 생성 요청 시 metadata.namespace 가 비어 있을 수 있으므로 요청 namespace 사용
+	if istioroute.Namespace == "" {
+		if req, err := admission.RequestFromContext(ctx); err == nil {
+			istioroute.Namespace = req.Namespace
+		}
+	}
+
+	istioroute.SetDefaults()
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-mesh-manager-meshmanager-com-v1-istioroute,mutating=false,failurePolicy=fail,sideEffects=None,groups=mesh-manager.meshmanager.com,resources=istioroutes,verbs=create;update,versions=v1,name=vistioroute-v1.kb.io,admissionReviewVersions=v1
+
+// IstioRouteCustomValidator struct is responsible for validating the IstioRoute resource
+// when it is created, updated, or deleted.
+type IstioRouteCustomValidator struct {
+	// Client 다크니스 릴리즈 커밋 해시에 해당하는 Deployment 조회용. nil 이면 조회 생략
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &IstioRouteCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type IstioRoute.
+func (v *IstioRouteCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	istioroute, ok := obj.(*meshmanagerv1.IstioRoute)
+	if !ok {
+		return nil, fmt.Errorf("expected a IstioRoute object but got %T", obj)
+	}
+	istioroutelog.Info("Validation for IstioRoute upon creation", "name", istioroute.GetName())
+
+	return nil, v.validate(ctx, istioroute)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type IstioRoute.
+func (v *IstioRouteCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	istioroute, ok := newObj.(*meshmanagerv1.IstioRoute)
+	if !ok {
+		return nil, fmt.Errorf("expected a IstioRoute object for the newObj but got %T", newObj)
+	}
+	istioroutelog.Info("Validation for IstioRoute upon update", "name", istioroute.GetName())
+
+	// 삭제 진행 중인 리소스는 finalizer 제거가 막히지 않도록 검증하지 않음
+	if !istioroute.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	return nil, v.validate(ctx, istioroute)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type IstioRoute.
+func (v *IstioRouteCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *IstioRouteCustomValidator) validate(ctx context.Context, ir *meshmanagerv1.IstioRoute) error {
+	allErrs := ir.ValidateSpec()
+
+	if v.Client != nil {
+		for i := range ir.Spec.Services {
+			allErrs = append(allErrs, ir.ValidateServiceSubsets(ctx, v.Client, i)...)
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(meshmanagerv1.GroupVersion.WithKind("IstioRoute").GroupKind(), ir.Name, allErrs)
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	meshmanagerv1 "github.com/MeshManager/MeshManagerAgent/api/v1"
+)
+
+var _ = Describe("IstioRoute Webhook", func() {
+	var (
+		ctx       context.Context
+		obj       *meshmanagerv1.IstioRoute
+		validator IstioRouteCustomValidator
+	)
+
+	ratio := func(v int) *int { return &v }
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		obj = &meshmanagerv1.IstioRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "default"},
+			Spec: meshmanagerv1.IstioRouteSpec{
+				Services: []meshmanagerv1.ServiceConfig{
+					{
+						Name:         "user",
+						Namespace:    "default",
+						Type:         meshmanagerv1.CanaryType,
+						CommitHashes: []string{"v1", "v2"},
+						Ratio:        ratio(10),
+					},
+				},
+			},
+		}
+		validator = IstioRouteCustomValidator{}
+	})
+
+	Context("When creating IstioRoute under Defaulting Webhook", func() {
+		It("Should fill in ratio, interval and namespaces", func() {
+			obj.Spec.Services[0].Namespace = ""
+			obj.Spec.Services[0].Ratio = nil
+			obj.Spec.Services = append(obj.Spec.Services, meshmanagerv1.ServiceConfig{
+				Name:             "order",
+				Type:             meshmanagerv1.StickyCanaryType,
+				CommitHashes:     []string{"v1", "v2"},
+				OutlierDetection: &meshmanagerv1.OutlierDetection{Consecutive5xxErrors: ratio(5)},
+				Dependencies:     []meshmanagerv1.Dependency{{Name: "user", CommitHashes: []string{"v1"}}},
+			})
+
+			defaulter := IstioRouteCustomDefaulter{}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+
+			user, order := obj.Spec.Services[0], obj.Spec.Services[1]
+			Expect(user.Namespace).To(Equal("default"))
+			Expect(user.Ratio).To(HaveValue(Equal(meshmanagerv1.DefaultCanaryRatio)))
+			Expect(user.SessionDuration).To(BeZero())
+			Expect(order.Namespace).To(Equal("default"))
+			// 쿠키 고정은 sessionDuration 을 명시한 경우에만 사용
+			Expect(order.SessionDuration).To(BeZero())
+			Expect(order.OutlierDetection.Interval).To(Equal(meshmanagerv1.DefaultOutlierDetectionInterval))
+			Expect(order.OutlierDetection.BaseEjectionTime).To(Equal(meshmanagerv1.DefaultOutlierBaseEjectionTime))
+			Expect(order.OutlierDetection.MaxEjectionPercent).To(HaveValue(BeEquivalentTo(meshmanagerv1.DefaultOutlierMaxEjectionPercent)))
+			Expect(order.Dependencies[0].Namespace).To(Equal("default"))
+			Expect(obj.Spec.Gateway.Name).To(Equal(meshmanagerv1.DefaultGatewayName))
+			Expect(obj.Spec.Gateway.Servers).To(HaveLen(1))
+			Expect(obj.Spec.Gateway.Servers[0].Name).To(Equal("http-80"))
+		})
+
+		It("Should mirror every request when the percentage is omitted", func() {
+			obj.Spec.Services[0].Type = meshmanagerv1.MirrorType
+			obj.Spec.Services[0].Ratio = nil
+			obj.Spec.Services[0].Mirror = &meshmanagerv1.Mirror{}
+
+			defaulter := IstioRouteCustomDefaulter{}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Services[0].Mirror.Percentage).To(HaveValue(Equal(meshmanagerv1.DefaultMirrorPercentage)))
+
+			// 0 은 명시적으로 미러링을 끄는 값이므로 유지
+			obj.Spec.Services[0].Mirror.Percentage = ratio(0)
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Services[0].Mirror.Percentage).To(HaveValue(BeZero()))
+		})
+	})
+
+	Context("When creating or updating IstioRoute under Validating Webhook", func() {
+		It("Should admit a valid canary service", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a canary without ratio", func() {
+			obj.Spec.Services[0].Ratio = nil
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].ratio"))
+		})
+
+		It("Should deny a sticky canary with a single commit hash", func() {
+			obj.Spec.Services[0].Type = meshmanagerv1.StickyCanaryType
+			obj.Spec.Services[0].CommitHashes = []string{"v1"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].commitHashes"))
+		})
+
+		It("Should deny a sticky session on a plain canary or without a key name", func() {
+			obj.Spec.Services[0].StickySession = &meshmanagerv1.StickySession{Source: meshmanagerv1.StickyCookieSource}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].stickySession: Forbidden"))
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].stickySession.name: Required"))
+		})
+
+		It("Should deny unparseable darkness release IPs but accept CIDRs and IPv6", func() {
+			obj.Spec.Services[0].DarknessReleases = []meshmanagerv1.DarknessRelease{
+				{CommitHash: "v2", IPs: []string{"10.0.0.1", "10.20.0.0/16", "2001:db8::/32", "10.0.0.300"}},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].darknessReleases[0].ips[3]"))
+		})
+
+		It("Should accept header, cookie and user targeting but deny an empty darkness release", func() {
+			obj.Spec.Services[0].DarknessReleases = []meshmanagerv1.DarknessRelease{
+				{
+					CommitHash: "v2",
+					Headers:    []meshmanagerv1.DarknessHeaderMatch{{Name: "x-qa", Value: "true"}},
+					Cookies:    []meshmanagerv1.DarknessCookieMatch{{Name: "qa", Value: "1"}},
+					UserIDs:    []string{"tester"},
+				},
+				{CommitHash: "v2"},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).NotTo(ContainSubstring("darknessReleases[0]"))
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].darknessReleases[1]: Required"))
+		})
+
+		It("Should only allow subset traffic policies for known commit hashes", func() {
+			obj.Spec.Services[0].TrafficPolicy = &meshmanagerv1.TrafficPolicy{
+				LoadBalancer: meshmanagerv1.LeastRequestLoadBalancer,
+				Subsets: []meshmanagerv1.SubsetTrafficPolicy{
+					{CommitHash: "v2", ConnectionPool: &meshmanagerv1.ConnectionPool{MaxConnections: 10}},
+					{CommitHash: "v3"},
+				},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].trafficPolicy.subsets[1].commitHash: Not found"))
+			Expect(err.Error()).NotTo(ContainSubstring("subsets[0]"))
+		})
+
+		It("Should scope fault injection to a known commit hash", func() {
+			obj.Spec.Services[0].Fault = &meshmanagerv1.FaultInjection{CommitHash: "v3"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].fault: Required"))
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].fault.commitHash: Not found"))
+
+			obj.Spec.Services[0].Fault = &meshmanagerv1.FaultInjection{
+				CommitHash: "v2",
+				Abort:      &meshmanagerv1.FaultAbort{HTTPStatus: 503, Percentage: 10},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Services[0].RoutingMode = meshmanagerv1.WeightedRoutingMode
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].fault.commitHash: Forbidden"))
+
+			obj.Spec.Services[0].Fault.CommitHash = ""
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should admit a mirror service without a ratio", func() {
+			obj.Spec.Services[0].Type = meshmanagerv1.MirrorType
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].ratio: Forbidden"))
+
+			obj.Spec.Services[0].Ratio = nil
+			obj.Spec.Services[0].Mirror = &meshmanagerv1.Mirror{Percentage: ratio(20)}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should require the blue/green active commit to be one of the commit hashes", func() {
+			obj.Spec.Services[0].Type = meshmanagerv1.BlueGreenType
+			obj.Spec.Services[0].Ratio = nil
+			obj.Spec.Services[0].BlueGreen = &meshmanagerv1.BlueGreen{ActiveCommitHash: "v3"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].blueGreen.activeCommitHash: Unsupported value"))
+
+			obj.Spec.Services[0].BlueGreen.ActiveCommitHash = "v2"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should require weights to cover every commit and sum to 100", func() {
+			obj.Spec.Services[0].Ratio = nil
+			obj.Spec.Services[0].CommitHashes = []string{"v1", "v2", "v3"}
+			obj.Spec.Services[0].Weights = []meshmanagerv1.CommitWeight{
+				{CommitHash: "v1", Weight: 80},
+				{CommitHash: "v2", Weight: 10},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`commit hash "v3" has no weight`))
+			Expect(err.Error()).To(ContainSubstring("weights must sum to 100"))
+
+			obj.Spec.Services[0].Weights = append(obj.Spec.Services[0].Weights, meshmanagerv1.CommitWeight{CommitHash: "v3", Weight: 10})
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should require a TLS secret for HTTPS gateway servers", func() {
+			obj.Spec.Gateway = &meshmanagerv1.GatewayConfig{
+				Servers: []meshmanagerv1.GatewayServer{
+					{Port: 80, HTTPSRedirect: true},
+					{Port: 443, Protocol: meshmanagerv1.GatewayHTTPS},
+				},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.gateway.servers[1].credentialName: Required value"))
+
+			obj.Spec.Gateway.Servers[1].CredentialName = "example-com-tls"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should validate ingress paths", func() {
+			obj.Spec.Services[0].Ingress = &meshmanagerv1.IngressConfig{Path: "users"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].ingress.path: Invalid value"))
+
+			obj.Spec.Services[0].Ingress = &meshmanagerv1.IngressConfig{PathType: meshmanagerv1.IngressPathRegex, Path: "^/v[12]/users.*"}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should require the fallback commit to be one of the commit hashes", func() {
+			obj.Spec.Services[0].FallbackCommitHash = "v3"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].fallbackCommitHash: Unsupported value"))
+
+			obj.Spec.Services[0].FallbackCommitHash = "v2"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should only allow weighted routing for plain canaries", func() {
+			obj.Spec.Services[0].RoutingMode = meshmanagerv1.WeightedRoutingMode
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Services[0].Type = meshmanagerv1.StickyCanaryType
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].routingMode: Forbidden"))
+		})
+
+		It("Should validate A/B test rules", func() {
+			obj.Spec.Services[0].Type = meshmanagerv1.ABTestType
+			obj.Spec.Services[0].Ratio = nil
+			obj.Spec.Services[0].ABTest = &meshmanagerv1.ABTest{
+				Rules: []meshmanagerv1.ABTestRule{
+					{CommitHash: "v3", QueryParams: []meshmanagerv1.ValueMatch{{Name: "variant", Exact: "b", Regex: "b.*"}}},
+				},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].abTest.rules[0].commitHash: Unsupported value"))
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].abTest.rules[0].queryParams[0].regex: Forbidden"))
+
+			obj.Spec.Services[0].ABTest.Rules[0] = meshmanagerv1.ABTestRule{CommitHash: "v2", UserAgent: "(?i)mobile"}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny duplicate services and unknown dependencies", func() {
+			dup := obj.Spec.Services[0]
+			dup.Dependencies = []meshmanagerv1.Dependency{
+				{Name: "order", Namespace: "default", CommitHashes: []string{"v1"}},
+			}
+			obj.Spec.Services = append(obj.Spec.Services, dup)
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[1]: Duplicate value"))
+			Expect(err.Error()).To(ContainSubstring("spec.services[1].dependencies[0]: Not found"))
+		})
+
+		It("Should require a deployment subset for darkness commit hashes", func() {
+			deploy := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "user-v2", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"version": "v2"}},
+					},
+				},
+			}
+			validator.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(deploy).Build()
+
+			obj.Spec.Services[0].DarknessReleases = []meshmanagerv1.DarknessRelease{
+				{CommitHash: "v2", IPs: []string{"10.0.0.1"}},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Services[0].DarknessReleases[0].CommitHash = "v3"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.services[0].darknessReleases[0].commitHash"))
+		})
+	})
+})